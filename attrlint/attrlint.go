@@ -0,0 +1,217 @@
+// Package attrlint defines an analyzer that checks span attribute keys
+// against the apmattr catalogue.
+//
+// It inspects every constant key passed to the go.opentelemetry.io/otel/attribute
+// constructors (attribute.String, attribute.Int64Slice, ...), converted with
+// attribute.Key or set in an attribute.KeyValue literal, which covers the
+// arguments of trace.WithAttributes and Span.SetAttributes. It reports keys
+// outside the apm. namespace, keys that are not dot-separated snake_case,
+// keys missing from the catalogue (suggesting the closest catalogued key) and
+// catalogued keys built with a constructor of the wrong type.
+//
+// Keys computed at runtime, such as those from attribute rule files, are not
+// constants and are left to apmattr.Lookup.
+package attrlint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strings"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// attributePkg is the import path of the OpenTelemetry attribute package
+const attributePkg = "go.opentelemetry.io/otel/attribute"
+
+// maxSuggestDistance is the largest edit distance at which an unknown key is
+// reported as a near-duplicate of a catalogued key
+const maxSuggestDistance = 3
+
+// Analyzer reports unregistered and malformed span attribute keys
+var Analyzer = &analysis.Analyzer{
+	Name:     "attrlint",
+	Doc:      "report span attribute keys that are malformed or missing from the apmattr catalogue",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// constructors maps the attribute package constructors to the type they build
+var constructors = map[string]attribute.Type{
+	"String":       attribute.STRING,
+	"Int":          attribute.INT64,
+	"Int64":        attribute.INT64,
+	"Bool":         attribute.BOOL,
+	"Float64":      attribute.FLOAT64,
+	"StringSlice":  attribute.STRINGSLICE,
+	"IntSlice":     attribute.INT64SLICE,
+	"Int64Slice":   attribute.INT64SLICE,
+	"BoolSlice":    attribute.BOOLSLICE,
+	"Float64Slice": attribute.FLOAT64SLICE,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	// The catalogue itself builds keys from its declarations
+	if pass.Pkg.Path() == "otelkit/apmattr" {
+		return nil, nil
+	}
+
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	filter := []ast.Node{(*ast.CallExpr)(nil), (*ast.CompositeLit)(nil)}
+
+	insp.Preorder(filter, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			checkCall(pass, n)
+		case *ast.CompositeLit:
+			checkKeyValueLit(pass, n)
+		}
+	})
+
+	return nil, nil
+}
+
+// checkCall checks attribute constructor calls and attribute.Key conversions
+func checkCall(pass *analysis.Pass, call *ast.CallExpr) {
+	if len(call.Args) == 0 {
+		return
+	}
+
+	// attribute.Key("apm.x") conversion
+	if tv, ok := pass.TypesInfo.Types[call.Fun]; ok && tv.IsType() {
+		if isAttributeKey(tv.Type) && len(call.Args) == 1 {
+			checkKey(pass, call.Args[0], 0, false)
+		}
+		return
+	}
+
+	fn, ok := calledFunc(pass, call.Fun)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != attributePkg {
+		return
+	}
+	if sig, ok := fn.Type().(*types.Signature); !ok || sig.Recv() != nil {
+		return
+	}
+	if typ, ok := constructors[fn.Name()]; ok {
+		checkKey(pass, call.Args[0], typ, true)
+	}
+}
+
+// checkKeyValueLit checks the Key field of attribute.KeyValue literals
+func checkKeyValueLit(pass *analysis.Pass, lit *ast.CompositeLit) {
+	named, ok := pass.TypesInfo.TypeOf(lit).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != attributePkg || named.Obj().Name() != "KeyValue" {
+		return
+	}
+
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if id, ok := kv.Key.(*ast.Ident); ok && id.Name == "Key" {
+				checkKey(pass, kv.Value, 0, false)
+			}
+		}
+	}
+}
+
+// checkKey reports problems with a constant key expression; typ is only
+// compared with the catalogue when typed is set
+func checkKey(pass *analysis.Pass, expr ast.Expr, typ attribute.Type, typed bool) {
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return
+	}
+	key := constant.StringVal(tv.Value)
+
+	switch {
+	case !strings.HasPrefix(key, apmattr.Prefix):
+		pass.Reportf(expr.Pos(), "attribute key %q is outside the %s namespace%s", key, apmattr.Prefix, suggestion(key))
+		return
+	case !apmattr.ValidKey(key):
+		pass.Reportf(expr.Pos(), "attribute key %q is not dot-separated snake_case%s", key, suggestion(key))
+		return
+	}
+
+	def, ok := apmattr.Lookup(key)
+	if !ok {
+		pass.Reportf(expr.Pos(), "attribute key %q is not declared in the apmattr catalogue%s", key, suggestion(key))
+		return
+	}
+
+	if typed && def.Type != typ {
+		pass.Reportf(expr.Pos(), "attribute key %q is catalogued as %s but built as %s", key, def.Type, typ)
+	}
+}
+
+// suggestion names the closest catalogued key when it is a likely typo.
+// Case and a missing apm. prefix are not counted as edits.
+func suggestion(key string) string {
+	candidates := []string{strings.ToLower(key)}
+	if !strings.HasPrefix(candidates[0], apmattr.Prefix) {
+		candidates = append(candidates, apmattr.Prefix+candidates[0])
+	}
+
+	best, bestDistance := "", maxSuggestDistance+1
+	for _, def := range apmattr.All() {
+		for _, candidate := range candidates {
+			if d := distance(candidate, string(def.Key)); d < bestDistance {
+				best, bestDistance = string(def.Key), d
+			}
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("; did you mean %q?", best)
+}
+
+// distance is the Levenshtein edit distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+// calledFunc returns the function a call expression calls
+func calledFunc(pass *analysis.Pass, fun ast.Expr) (*types.Func, bool) {
+	var id *ast.Ident
+	switch fun := ast.Unparen(fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil, false
+	}
+
+	fn, ok := pass.TypesInfo.Uses[id].(*types.Func)
+	return fn, ok
+}
+
+// isAttributeKey reports whether t is attribute.Key
+func isAttributeKey(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == attributePkg && named.Obj().Name() == "Key"
+}
@@ -0,0 +1,16 @@
+// Command attrlint checks span attribute keys against the apmattr catalogue.
+// It runs as a go vet tool from any module that uses otelkit:
+//
+//	go build -o /tmp/attrlint attrlint/cmd/attrlint
+//	go vet -vettool=/tmp/attrlint ./...
+package main
+
+import (
+	"attrlint"
+
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(attrlint.Analyzer)
+}
@@ -0,0 +1,16 @@
+module attrlint
+
+go 1.24.0
+
+require (
+	go.opentelemetry.io/otel v1.24.0
+	golang.org/x/tools v0.40.0
+	otelkit v0.0.0
+)
+
+require (
+	golang.org/x/mod v0.31.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
+)
+
+replace otelkit => ../otelkit
@@ -0,0 +1,56 @@
+// Package demo holds the attribute patterns of oteltracer02/main.go
+package demo
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tableKey = "apm.db.tabel"
+
+func apiCallHandler(span trace.Span, err error) {
+	span.SetAttributes(
+		attribute.String("user.action", "button_click"),    // want `attribute key "user.action" is outside the apm. namespace; did you mean "apm.user.action"\?`
+		attribute.String("operation", "external_api_call"), // want `attribute key "operation" is outside the apm. namespace; did you mean "apm.operation"\?`
+		attribute.String("apm.custom.attribute", "demo_value"),
+		attribute.String("app.component", "api_handler"), // want `attribute key "app.component" is outside the apm. namespace; did you mean "apm.component"\?`
+		attribute.String("apm.business.operation", "fetch_post_data"),
+	)
+
+	span.SetAttributes(
+		attribute.Bool("sapm.uccess", true), // want `attribute key "sapm.uccess" is outside the apm. namespace; did you mean "apm.success"\?`
+		attribute.String("apm.result.type", "json"),
+	)
+}
+
+func callExternalAPI(span trace.Span, status int) trace.SpanStartOption {
+	span.SetAttributes(
+		attribute.String("error.type", "response_read_failed"), // want `attribute key "error.type" is outside the apm. namespace; did you mean "apm.error.type"\?`
+		attribute.Int("apm.external.api.status_code", status),
+		attribute.String("apm.external.api.duration_ms", "12"), // want `attribute key "apm.external.api.duration_ms" is catalogued as INT64 but built as STRING`
+		attribute.Int64("apm.external.api.latency_ms", 12),     // want `attribute key "apm.external.api.latency_ms" is not declared in the apmattr catalogue$`
+		attribute.String(tableKey, "go_user_tbl"),              // want `attribute key "apm.db.tabel" is not declared in the apmattr catalogue; did you mean "apm.db.table"\?`
+	)
+
+	return trace.WithAttributes(
+		attribute.String("apm.External.API.url", "https://example.com"),                     // want `attribute key "apm.External.API.url" is not dot-separated snake_case; did you mean "apm.external.api.url"\?`
+		attribute.Key("apm.data-type").String("post"),                                       // want `attribute key "apm.data-type" is not dot-separated snake_case; did you mean "apm.data.type"\?`
+		attribute.KeyValue{Key: "apm.response.parsd", Value: attribute.StringValue("true")}, // want `attribute key "apm.response.parsd" is not declared in the apmattr catalogue; did you mean "apm.response.parsed"\?`
+	)
+}
+
+func testAttributesHandler(span trace.Span, dynamic string) {
+	span.SetAttributes(
+		attribute.Bool("apm.test.bool", true),
+		attribute.Int64("apm.test.int64", 9876543210),
+		attribute.Float64("apm.test.float64", 123.456),
+		attribute.BoolSlice("apm.test.bool_slice", []bool{true, false, true}),
+		attribute.Int64Slice("apm.test.int64_slice", []int64{10, 20, 30}),
+		attribute.Float64Slice("apm.test.float64_slice", []float64{1.5, 2.5, 3.5}),
+		attribute.StringSlice("apm.test.string_slice", []string{"apple", "banana", "cherry"}),
+		attribute.Float64Slice("apm.test.int64_slice", nil), // want `attribute key "apm.test.int64_slice" is catalogued as INT64SLICE but built as FLOAT64SLICE`
+
+		// Runtime keys are checked by apmattr.Lookup instead
+		attribute.String(dynamic, "value"),
+	)
+}
@@ -0,0 +1,27 @@
+// Package attribute is a stub of go.opentelemetry.io/otel/attribute
+package attribute
+
+type Key string
+
+type Value struct{}
+
+type KeyValue struct {
+	Key   Key
+	Value Value
+}
+
+func (k Key) String(v string) KeyValue { return KeyValue{Key: k} }
+func (k Key) Bool(v bool) KeyValue     { return KeyValue{Key: k} }
+func (k Key) Int(v int) KeyValue       { return KeyValue{Key: k} }
+
+func String(k, v string) KeyValue                 { return KeyValue{Key: Key(k)} }
+func Int(k string, v int) KeyValue                { return KeyValue{Key: Key(k)} }
+func Int64(k string, v int64) KeyValue            { return KeyValue{Key: Key(k)} }
+func Bool(k string, v bool) KeyValue              { return KeyValue{Key: Key(k)} }
+func Float64(k string, v float64) KeyValue        { return KeyValue{Key: Key(k)} }
+func StringSlice(k string, v []string) KeyValue   { return KeyValue{Key: Key(k)} }
+func Int64Slice(k string, v []int64) KeyValue     { return KeyValue{Key: Key(k)} }
+func BoolSlice(k string, v []bool) KeyValue       { return KeyValue{Key: Key(k)} }
+func Float64Slice(k string, v []float64) KeyValue { return KeyValue{Key: Key(k)} }
+
+func StringValue(v string) Value { return Value{} }
@@ -0,0 +1,13 @@
+// Package trace is a stub of go.opentelemetry.io/otel/trace
+package trace
+
+import "go.opentelemetry.io/otel/attribute"
+
+type Span interface {
+	SetAttributes(kv ...attribute.KeyValue)
+	End()
+}
+
+type SpanStartOption interface{}
+
+func WithAttributes(attributes ...attribute.KeyValue) SpanStartOption { return nil }
@@ -17,22 +17,25 @@ const docTemplate = `{
     "paths": {
         "/users": {
             "get": {
-                "description": "Retrieve a list of all users",
+                "description": "Retrieve a page of users with cursor or offset pagination, sorting and filtering",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "users"
                 ],
-                "summary": "Get all users",
+                "summary": "List users",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.User"
-                            }
+                            "$ref": "#/definitions/users.UserPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination, sort or filter parameters",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "500": {
@@ -40,8 +43,81 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
-                }
+                },
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (1-500)",
+                        "name": "limit",
+                        "in": "query",
+                        "default": 50
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of users to skip (offset pagination)",
+                        "name": "offset",
+                        "in": "query",
+                        "default": 0
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from next_cursor (keyset pagination on username, requires sort=username or -username)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field, prefix with - for descending",
+                        "name": "sort",
+                        "in": "query",
+                        "default": "username",
+                        "enum": [
+                            "username",
+                            "-username",
+                            "name",
+                            "-name",
+                            "email",
+                            "-email",
+                            "age",
+                            "-age",
+                            "created_at",
+                            "-created_at",
+                            "updated_at",
+                            "-updated_at"
+                        ]
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive name substring filter",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive email substring filter",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum age filter",
+                        "name": "min_age",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum age filter",
+                        "name": "max_age",
+                        "in": "query"
+                    }
+                ]
             },
             "post": {
                 "description": "Create a new user with the provided details",
@@ -62,7 +138,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.CreateUserRequest"
+                            "$ref": "#/definitions/users.CreateUserRequest"
                         }
                     }
                 ],
@@ -70,7 +146,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.User"
+                            "$ref": "#/definitions/users.User"
                         }
                     },
                     "400": {
@@ -79,11 +155,29 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Username or email already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "User data rejected by the database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Error creating user",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
@@ -111,7 +205,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.User"
+                            "$ref": "#/definitions/users.User"
                         }
                     },
                     "400": {
@@ -131,6 +225,12 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
@@ -160,7 +260,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.UpdateUserRequest"
+                            "$ref": "#/definitions/users.UpdateUserRequest"
                         }
                     }
                 ],
@@ -168,7 +268,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.User"
+                            "$ref": "#/definitions/users.User"
                         }
                     },
                     "400": {
@@ -183,11 +283,29 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Email already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "User data rejected by the database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Error updating user",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
@@ -227,13 +345,19 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         }
     },
     "definitions": {
-        "main.CreateUserRequest": {
+        "users.CreateUserRequest": {
             "type": "object",
             "properties": {
                 "age": {
@@ -253,7 +377,7 @@ const docTemplate = `{
                 }
             }
         },
-        "main.UpdateUserRequest": {
+        "users.UpdateUserRequest": {
             "type": "object",
             "properties": {
                 "age": {
@@ -269,7 +393,7 @@ const docTemplate = `{
                 }
             }
         },
-        "main.User": {
+        "users.User": {
             "type": "object",
             "properties": {
                 "age": {
@@ -296,6 +420,41 @@ const docTemplate = `{
                     "example": "johndoe"
                 }
             }
+        },
+        "users.UserPage": {
+            "type": "object",
+            "properties": {
+                "has_more": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 50
+                },
+                "next_cursor": {
+                    "type": "string",
+                    "example": "Ym9ic21pdGg"
+                },
+                "next_offset": {
+                    "type": "integer",
+                    "example": 50
+                },
+                "offset": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/users.User"
+                    }
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,19 @@
+// Command gendoc writes the apmattr attribute catalogue as markdown
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"otelkit/apmattr"
+)
+
+func main() {
+	out := flag.String("o", "ATTRIBUTES.md", "output file")
+	flag.Parse()
+
+	if err := os.WriteFile(*out, apmattr.Markdown(), 0o644); err != nil {
+		log.Fatalf("Error writing %s: %v", *out, err)
+	}
+}
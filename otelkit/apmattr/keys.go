@@ -0,0 +1,129 @@
+package apmattr
+
+// HTTP request attributes
+var (
+	HTTPMethod     = stringKey("apm.http.method", PIINone, Stable, "HTTP request method")
+	HTTPURL        = stringKey("apm.http.url", PIINone, Stable, "Request URL path and query")
+	HTTPRoute      = stringKey("apm.http.route", PIINone, Stable, "Matched route template, e.g. /users/{username}")
+	HTTPHandler    = stringKey("apm.http.handler", PIINone, Stable, "Handler method the route dispatched to")
+	HTTPStatusCode = intKey("apm.http.status_code", PIINone, Stable, "HTTP response status code")
+)
+
+// Outbound HTTP client attributes set by httpclient
+var (
+	HTTPClientResendReason = stringKey("apm.http.client.resend_reason", PIINone, Stable, "Why the request was sent again, retry or redirect")
+)
+
+// Operation and user attributes
+var (
+	Operation         = stringKey("apm.operation", PIINone, Stable, "Business operation handled by the span, e.g. get_user")
+	Component         = stringKey("apm.component", PIINone, Experimental, "Application component that started the span")
+	BusinessOperation = stringKey("apm.business.operation", PIINone, Experimental, "Business level description of the operation")
+	UserAction        = stringKey("apm.user.action", PIINone, Experimental, "UI action that triggered the request")
+	UserUsername      = stringKey("apm.user.username", PIIIdentifier, Stable, "Username the request acts on")
+	UserEmail         = stringKey("apm.user.email", PIIPersonal, Stable, "Email address of the user being created")
+	UserTier          = stringKey("apm.user.tier", PIINone, Experimental, "Customer tier from the X-User-Tier header")
+	UserAge           = intKey("apm.user.age", PIIPersonal, Experimental, "Age from the request body")
+	ClientID          = stringKey("apm.client.id", PIINone, Experimental, "Calling client from the client_id query parameter")
+	ServiceFlavor     = stringKey("apm.service.flavor", PIINone, Experimental, "Instrumentation flavor of the service")
+)
+
+// Attributes promoted from W3C Baggage by baggageattr
+var (
+	TenantID     = stringKey("apm.tenant.id", PIINone, Experimental, "Tenant from the tenant.id baggage member set by the gateway")
+	CustomerTier = stringKey("apm.customer.tier", PIINone, Experimental, "Customer tier from the customer.tier baggage member set by the gateway")
+)
+
+// Database attributes
+var (
+	DBSystem                 = stringKey("apm.db.system", PIINone, Stable, "Database system, e.g. postgresql, sqlite or memory")
+	DBStatement              = stringKey("apm.db.statement", PIINone, Deprecated, "SQL statement text; sqltrace sets the normalized db.statement instead")
+	DBOperation              = stringKey("apm.db.operation", PIINone, Stable, "SQL operation, e.g. SELECT")
+	DBTable                  = stringKey("apm.db.table", PIINone, Stable, "Table the statement operates on")
+	DBQueryParameterUsername = stringKey("apm.db.query.parameter.username", PIIIdentifier, Deprecated, "Username bound to the statement; bound parameters are no longer recorded")
+	DBPageSize               = intKey("apm.db.page_size", PIINone, Stable, "Maximum number of rows requested")
+	DBFilters                = stringSliceKey("apm.db.filters", PIINone, Stable, "Names of the filters applied to a list query")
+	DBSort                   = stringKey("apm.db.sort", PIINone, Stable, "Column a list query is sorted by")
+	DBPagination             = stringKey("apm.db.pagination", PIINone, Stable, "Pagination strategy, offset or cursor")
+	DBRowsReturned           = intKey("apm.db.rows_returned", PIINone, Stable, "Number of rows returned")
+	DBConnectionWaitMS       = float64Key("apm.db.connection.wait_ms", PIINone, Stable, "Time the operation waited for a pooled connection in milliseconds")
+)
+
+// Database client attributes set by sqltrace
+var (
+	DBRowsAffected         = intKey("apm.db.rows_affected", PIINone, Stable, "Number of rows changed by the statement")
+	DBDurationMS           = float64Key("apm.db.duration_ms", PIINone, Stable, "Time the driver took to run the call in milliseconds")
+	DBStatementFingerprint = stringKey("apm.db.statement.fingerprint", PIINone, Stable, "Hash of the normalized statement, shared by statements of the same shape")
+)
+
+// Database connection attributes set on the db.connect spans
+var (
+	DBConnectAttempt   = intKey("apm.db.connect.attempt", PIINone, Stable, "Number of the connection attempt, starting at 1")
+	DBConnectBackoffMS = float64Key("apm.db.connect.backoff_ms", PIINone, Stable, "Time waited before the next attempt after a failure in milliseconds")
+)
+
+// Error attributes set by instrument.RecordError
+var (
+	Error           = boolKey("apm.error", PIINone, Stable, "Whether the operation failed")
+	ErrorType       = stringKey("apm.error.type", PIINone, Stable, "Low cardinality error classification, e.g. not_found")
+	ErrorMessage    = stringKey("apm.error.message", PIINone, Stable, "Error message")
+	ErrorClass      = stringKey("apm.error.class", PIINone, Stable, "client for 4xx errors, server for 5xx errors")
+	ErrorStatusCode = intKey("apm.error.status_code", PIINone, Stable, "HTTP status code the error maps to")
+)
+
+// Redaction attributes
+var (
+	RedactedKeys = stringSliceKey("apm.redacted.keys", PIINone, Stable, "Attribute keys whose values were dropped, masked, hashed or truncated")
+)
+
+// Attribute limit attributes set by attrlimit
+var (
+	LimitsTruncatedKeys = stringSliceKey("apm.limits.truncated.keys", PIINone, Stable, "Attribute keys whose values were truncated to the length limits")
+	LimitsDemotedKeys   = stringSliceKey("apm.limits.demoted.keys", PIINone, Stable, "High cardinality attribute keys moved to an event or dropped")
+	LimitsDroppedCount  = intKey("apm.limits.dropped.count", PIINone, Stable, "Number of attributes dropped by the per-span attribute limit")
+	LimitsAction        = stringKey("apm.limits.action", PIINone, Stable, "Action counted by the apm.limits.actions metric, truncate, drop or demote")
+)
+
+// Attribute rule engine attributes
+var (
+	RulesVersion = stringKey("apm.rules.version", PIINone, Stable, "Version of the attribute rule set that produced the span's attributes")
+)
+
+// Schema migration attributes
+var (
+	MigrationVersion   = intKey("apm.migration.version", PIINone, Stable, "Version of the migration step")
+	MigrationName      = stringKey("apm.migration.name", PIINone, Stable, "Name of the migration step")
+	MigrationDirection = stringKey("apm.migration.direction", PIINone, Stable, "Migration direction, up or down")
+	MigrationApplied   = intKey("apm.migration.applied", PIINone, Stable, "Number of migrations applied")
+	MigrationReverted  = intKey("apm.migration.reverted", PIINone, Stable, "Number of migrations reverted")
+)
+
+// External API call attributes
+var (
+	ExternalAPIURL                   = stringKey("apm.external.api.url", PIINone, Stable, "URL of the external API")
+	ExternalAPIMethod                = stringKey("apm.external.api.method", PIINone, Stable, "HTTP method of the external call")
+	ExternalAPIProvider              = stringKey("apm.external.api.provider", PIINone, Stable, "Provider of the external API")
+	ExternalAPIDurationMS            = intKey("apm.external.api.duration_ms", PIINone, Stable, "Duration of the external call in milliseconds")
+	ExternalAPIStatusCode            = intKey("apm.external.api.status_code", PIINone, Stable, "HTTP status code of the external response")
+	ExternalAPIStatus                = stringKey("apm.external.api.status", PIINone, Stable, "HTTP status line of the external response")
+	ExternalAPIResponseContentLength = intKey("apm.external.api.response.content_length", PIINone, Stable, "Content-Length of the external response, -1 when unknown")
+	ExternalAPIResponseBodySize      = intKey("apm.external.api.response.body_size_bytes", PIINone, Stable, "Bytes read from the external response body")
+	CustomRequestID                  = stringKey("apm.custom.request.id", PIINone, Experimental, "Demo request identifier")
+	DataType                         = stringKey("apm.data.type", PIINone, Experimental, "Kind of data fetched")
+	ResponseParsed                   = boolKey("apm.response.parsed", PIINone, Experimental, "Whether the response body was parsed")
+	ResultType                       = stringKey("apm.result.type", PIINone, Experimental, "Format of the result returned to the client")
+	Success                          = boolKey("apm.success", PIINone, Experimental, "Whether the request succeeded")
+	CustomAttribute                  = stringKey("apm.custom.attribute", PIINone, Experimental, "Demo custom attribute")
+)
+
+// Attribute type demonstration attributes
+var (
+	TestBool         = boolKey("apm.test.bool", PIINone, Experimental, "Demo bool attribute")
+	TestInt64        = intKey("apm.test.int64", PIINone, Experimental, "Demo int64 attribute")
+	TestFloat64      = float64Key("apm.test.float64", PIINone, Experimental, "Demo float64 attribute")
+	TestString       = stringKey("apm.test.string", PIINone, Experimental, "Demo string attribute")
+	TestBoolSlice    = boolSliceKey("apm.test.bool_slice", PIINone, Experimental, "Demo []bool attribute")
+	TestInt64Slice   = int64SliceKey("apm.test.int64_slice", PIINone, Experimental, "Demo []int64 attribute")
+	TestFloat64Slice = float64SliceKey("apm.test.float64_slice", PIINone, Experimental, "Demo []float64 attribute")
+	TestStringSlice  = stringSliceKey("apm.test.string_slice", PIINone, Experimental, "Demo []string attribute")
+)
@@ -0,0 +1,27 @@
+package apmattr
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Markdown renders the attribute catalogue as a markdown table
+func Markdown() []byte {
+	var b bytes.Buffer
+
+	b.WriteString("# Custom span attributes\n\n")
+	b.WriteString("<!-- Code generated by go generate ./apmattr; DO NOT EDIT. -->\n\n")
+	b.WriteString("Every custom attribute is declared in `otelkit/apmattr`. Add new keys there\n")
+	b.WriteString("and run `go generate ./apmattr` to refresh this file.\n\n")
+	b.WriteString("| Key | Type | PII | Stability | Description |\n")
+	b.WriteString("|-----|------|-----|-----------|-------------|\n")
+
+	for _, def := range All() {
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n",
+			def.Key, strings.ToLower(def.Type.String()), def.PII, def.Stability,
+			strings.ReplaceAll(def.Description, "|", `\|`))
+	}
+
+	return b.Bytes()
+}
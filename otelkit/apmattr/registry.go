@@ -0,0 +1,213 @@
+// Package apmattr is the catalogue of custom span attributes. Every apm.*
+// attribute is declared here once as a typed key, so a misspelled key or a
+// value of the wrong type is a compile error instead of a broken dashboard.
+//
+// Keys that only become known at runtime, such as those in attribute rule
+// files, are checked against the catalogue with Lookup.
+package apmattr
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+//go:generate go run ./internal/gendoc -o ATTRIBUTES.md
+
+// Prefix is the namespace of every custom attribute key
+const Prefix = "apm."
+
+// keyPattern is the accepted shape of a custom key: lowercase dot-separated
+// segments of letters, digits and underscores under the apm. prefix
+var keyPattern = regexp.MustCompile(`^apm(\.[a-z][a-z0-9_]*)+$`)
+
+// PII classifies what an attribute value reveals about a person
+type PII string
+
+// PII classifications
+const (
+	// PIINone values never identify a person
+	PIINone PII = "none"
+	// PIIIdentifier values identify an account, e.g. a username
+	PIIIdentifier PII = "identifier"
+	// PIIPersonal values are personal data, e.g. an email address
+	PIIPersonal PII = "personal"
+)
+
+// Stability is the compatibility promise for an attribute
+type Stability string
+
+// Stability levels
+const (
+	// Stable attributes are relied on by dashboards and alerts and are not renamed
+	Stable Stability = "stable"
+	// Experimental attributes may change or be removed
+	Experimental Stability = "experimental"
+	// Deprecated attributes are no longer set; they stay catalogued so that
+	// redaction and linting still know them while old data ages out
+	Deprecated Stability = "deprecated"
+)
+
+// Definition describes a catalogued attribute
+type Definition struct {
+	Key         attribute.Key
+	Type        attribute.Type
+	Description string
+	PII         PII
+	Stability   Stability
+}
+
+// registry holds every definition by key
+var registry = map[attribute.Key]Definition{}
+
+// define registers an attribute; keys are declared at init time, so a
+// duplicate or malformed key panics before the process does any work
+func define(key string, typ attribute.Type, pii PII, stability Stability, description string) attribute.Key {
+	if !ValidKey(key) {
+		panic(fmt.Sprintf("apmattr: malformed attribute key %q", key))
+	}
+	if _, ok := registry[attribute.Key(key)]; ok {
+		panic(fmt.Sprintf("apmattr: attribute key %q defined twice", key))
+	}
+
+	registry[attribute.Key(key)] = Definition{
+		Key:         attribute.Key(key),
+		Type:        typ,
+		Description: description,
+		PII:         pii,
+		Stability:   stability,
+	}
+	return attribute.Key(key)
+}
+
+// ValidKey reports whether key is a well-formed custom attribute key
+func ValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
+// Lookup returns the definition of key
+func Lookup(key string) (Definition, bool) {
+	def, ok := registry[attribute.Key(key)]
+	return def, ok
+}
+
+// All returns every definition sorted by key
+func All() []Definition {
+	defs := make([]Definition, 0, len(registry))
+	for _, def := range registry {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Key < defs[j].Key })
+	return defs
+}
+
+// StringKey is a catalogued string attribute
+type StringKey struct{ key attribute.Key }
+
+// Key returns the attribute key
+func (k StringKey) Key() attribute.Key { return k.key }
+
+// String returns the attribute with value v
+func (k StringKey) String(v string) attribute.KeyValue { return k.key.String(v) }
+
+// IntKey is a catalogued integer attribute, stored as int64
+type IntKey struct{ key attribute.Key }
+
+// Key returns the attribute key
+func (k IntKey) Key() attribute.Key { return k.key }
+
+// Int returns the attribute with value v
+func (k IntKey) Int(v int) attribute.KeyValue { return k.key.Int(v) }
+
+// Int64 returns the attribute with value v
+func (k IntKey) Int64(v int64) attribute.KeyValue { return k.key.Int64(v) }
+
+// BoolKey is a catalogued boolean attribute
+type BoolKey struct{ key attribute.Key }
+
+// Key returns the attribute key
+func (k BoolKey) Key() attribute.Key { return k.key }
+
+// Bool returns the attribute with value v
+func (k BoolKey) Bool(v bool) attribute.KeyValue { return k.key.Bool(v) }
+
+// Float64Key is a catalogued floating point attribute
+type Float64Key struct{ key attribute.Key }
+
+// Key returns the attribute key
+func (k Float64Key) Key() attribute.Key { return k.key }
+
+// Float64 returns the attribute with value v
+func (k Float64Key) Float64(v float64) attribute.KeyValue { return k.key.Float64(v) }
+
+// StringSliceKey is a catalogued string slice attribute
+type StringSliceKey struct{ key attribute.Key }
+
+// Key returns the attribute key
+func (k StringSliceKey) Key() attribute.Key { return k.key }
+
+// StringSlice returns the attribute with value v
+func (k StringSliceKey) StringSlice(v []string) attribute.KeyValue { return k.key.StringSlice(v) }
+
+// Int64SliceKey is a catalogued integer slice attribute
+type Int64SliceKey struct{ key attribute.Key }
+
+// Key returns the attribute key
+func (k Int64SliceKey) Key() attribute.Key { return k.key }
+
+// Int64Slice returns the attribute with value v
+func (k Int64SliceKey) Int64Slice(v []int64) attribute.KeyValue { return k.key.Int64Slice(v) }
+
+// BoolSliceKey is a catalogued boolean slice attribute
+type BoolSliceKey struct{ key attribute.Key }
+
+// Key returns the attribute key
+func (k BoolSliceKey) Key() attribute.Key { return k.key }
+
+// BoolSlice returns the attribute with value v
+func (k BoolSliceKey) BoolSlice(v []bool) attribute.KeyValue { return k.key.BoolSlice(v) }
+
+// Float64SliceKey is a catalogued floating point slice attribute
+type Float64SliceKey struct{ key attribute.Key }
+
+// Key returns the attribute key
+func (k Float64SliceKey) Key() attribute.Key { return k.key }
+
+// Float64Slice returns the attribute with value v
+func (k Float64SliceKey) Float64Slice(v []float64) attribute.KeyValue { return k.key.Float64Slice(v) }
+
+// Typed key constructors used by the declarations in keys.go
+
+func stringKey(key string, pii PII, stability Stability, description string) StringKey {
+	return StringKey{define(key, attribute.STRING, pii, stability, description)}
+}
+
+func intKey(key string, pii PII, stability Stability, description string) IntKey {
+	return IntKey{define(key, attribute.INT64, pii, stability, description)}
+}
+
+func boolKey(key string, pii PII, stability Stability, description string) BoolKey {
+	return BoolKey{define(key, attribute.BOOL, pii, stability, description)}
+}
+
+func float64Key(key string, pii PII, stability Stability, description string) Float64Key {
+	return Float64Key{define(key, attribute.FLOAT64, pii, stability, description)}
+}
+
+func stringSliceKey(key string, pii PII, stability Stability, description string) StringSliceKey {
+	return StringSliceKey{define(key, attribute.STRINGSLICE, pii, stability, description)}
+}
+
+func int64SliceKey(key string, pii PII, stability Stability, description string) Int64SliceKey {
+	return Int64SliceKey{define(key, attribute.INT64SLICE, pii, stability, description)}
+}
+
+func boolSliceKey(key string, pii PII, stability Stability, description string) BoolSliceKey {
+	return BoolSliceKey{define(key, attribute.BOOLSLICE, pii, stability, description)}
+}
+
+func float64SliceKey(key string, pii PII, stability Stability, description string) Float64SliceKey {
+	return Float64SliceKey{define(key, attribute.FLOAT64SLICE, pii, stability, description)}
+}
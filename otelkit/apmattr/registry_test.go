@@ -0,0 +1,99 @@
+package apmattr
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"apm.http.route", true},
+		{"apm.test.int64_slice", true},
+		{"apm.external.api.response.body_size_bytes", true},
+		{"sapm.uccess", false},
+		{"error.type", false},
+		{"apm.", false},
+		{"apm.HTTP.route", false},
+		{"apm.http..route", false},
+		{"apm.http.route.", false},
+		{"apm.http-route", false},
+		{"apm.1st", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidKey(tt.key); got != tt.want {
+			t.Errorf("ValidKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	def, ok := Lookup("apm.user.email")
+	if !ok {
+		t.Fatal("apm.user.email is not registered")
+	}
+	if def.Type != attribute.STRING || def.PII != PIIPersonal {
+		t.Errorf("apm.user.email = %+v", def)
+	}
+
+	if _, ok := Lookup("apm.unknown"); ok {
+		t.Error("Lookup found an unregistered key")
+	}
+}
+
+func TestTypedKeys(t *testing.T) {
+	tests := []struct {
+		kv   attribute.KeyValue
+		want attribute.Type
+	}{
+		{HTTPRoute.String("/users"), attribute.STRING},
+		{HTTPStatusCode.Int(200), attribute.INT64},
+		{Error.Bool(true), attribute.BOOL},
+		{TestFloat64.Float64(1.5), attribute.FLOAT64},
+		{DBFilters.StringSlice([]string{"name"}), attribute.STRINGSLICE},
+		{TestInt64Slice.Int64Slice([]int64{1}), attribute.INT64SLICE},
+		{TestBoolSlice.BoolSlice([]bool{true}), attribute.BOOLSLICE},
+		{TestFloat64Slice.Float64Slice([]float64{1}), attribute.FLOAT64SLICE},
+	}
+
+	// Each constructor must produce the type its definition declares
+	for _, tt := range tests {
+		def, ok := Lookup(string(tt.kv.Key))
+		if !ok {
+			t.Errorf("%s is not registered", tt.kv.Key)
+			continue
+		}
+		if tt.kv.Value.Type() != tt.want || def.Type != tt.want {
+			t.Errorf("%s: value type %s, definition type %s, want %s", tt.kv.Key, tt.kv.Value.Type(), def.Type, tt.want)
+		}
+	}
+}
+
+func TestDefinePanics(t *testing.T) {
+	for _, key := range []string{"apm.http.route", "http.route"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("define(%q) did not panic", key)
+				}
+			}()
+			define(key, attribute.STRING, PIINone, Stable, "duplicate")
+		}()
+	}
+}
+
+func TestMarkdownUpToDate(t *testing.T) {
+	data, err := os.ReadFile("ATTRIBUTES.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, Markdown()) {
+		t.Error("ATTRIBUTES.md is out of date, run go generate ./apmattr")
+	}
+}
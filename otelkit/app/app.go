@@ -0,0 +1,214 @@
+// Package app runs a user management service: it loads the configuration
+// from the environment and a .env file, sets up telemetry, opens the user
+// store selected by STORE, serves the user API next to the health,
+// readiness, diagnostics and API documentation endpoints, and shuts down
+// gracefully. otelapi and oteltracer differ only in the Config they pass
+// to Run.
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"otelkit/attrrules"
+	"otelkit/env"
+	"otelkit/instrument"
+	"otelkit/telemetry"
+	"otelkit/users"
+)
+
+// Config describes a service
+type Config struct {
+	// Name is the service name; it names the telemetry, the
+	// instrumentation scope and the migrate command
+	Name string
+	// DefaultMode is the instrumentation strategy used when
+	// INSTRUMENTATION_MODE is not set
+	DefaultMode instrument.Mode
+	// DefaultPort is the listen port used when PORT is not set
+	DefaultPort string
+	// Docs serves the API documentation under /swagger/; nil serves none
+	Docs http.Handler
+}
+
+// shutdownTimeout bounds how long in-flight requests and span export may take on exit
+const shutdownTimeout = 10 * time.Second
+
+// Run runs the service until SIGINT or SIGTERM, or manages the database
+// schema and returns when started as "<name> migrate up|down [steps]|status".
+// Configuration errors are fatal, as is failing to reach the database
+// within DB_CONNECT_TIMEOUT.
+func Run(cfg Config) {
+	// Load environment variables from .env file
+	env.Load(".env")
+
+	// Bootstrap the SDK TracerProvider when running without eBPF auto-instrumentation
+	ctx := context.Background()
+	shutdownTelemetry, err := telemetry.Setup(ctx, telemetry.ConfigFromEnv(cfg.Name))
+	if err != nil {
+		log.Fatalf("Failed to set up telemetry: %v", err)
+	}
+
+	// Select the instrumentation strategy
+	mode, err := instrument.ParseMode(env.String("INSTRUMENTATION_MODE", string(cfg.DefaultMode)))
+	if err != nil {
+		log.Fatalf("Invalid INSTRUMENTATION_MODE: %v", err)
+	}
+	instr := instrument.New(cfg.Name, mode)
+
+	store, err := openStore(instr)
+	if err != nil {
+		log.Fatalf("Failed to open user store: %v", err)
+	}
+	defer store.Close()
+
+	// "<name> migrate up|down [steps]|status" manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := store.migrateCommand(ctx, os.Args[2:])
+		shutdownTelemetry(ctx)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Background work such as the rule watcher stops on SIGINT or SIGTERM
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	port := env.String("PORT", cfg.DefaultPort)
+	handler := newHandler(sigCtx, cfg, store, instr)
+
+	// Start server
+	log.Printf("Starting server on port %s (instrumentation mode %s)", port, mode)
+	log.Print(store.description)
+	log.Println("Available endpoints:")
+	log.Println("  GET    /health")
+	log.Println("  GET    /ready")
+	log.Println("  GET    /debug/telemetry")
+	log.Println("  GET    /users")
+	log.Println("  POST   /users")
+	log.Println("  GET    /users/{username}")
+	log.Println("  PUT    /users/{username}")
+	if cfg.Docs != nil {
+		log.Printf("  GET    http://localhost:%s/swagger/", port)
+	}
+
+	srv := &http.Server{Addr: ":" + port, Handler: handler}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	}()
+
+	// Connect to the database in the background; giving up after
+	// DB_CONNECT_TIMEOUT shuts the server down and exits with an error
+	startupErr := make(chan error, 1)
+	go func() {
+		if err := store.start(sigCtx); err != nil {
+			startupErr <- err
+		}
+	}()
+
+	// Drain in-flight requests, then flush queued spans before exiting
+	var exitErr error
+	select {
+	case <-sigCtx.Done():
+	case exitErr = <-startupErr:
+		log.Printf("Startup failed: %v", exitErr)
+	}
+	log.Println("Shutting down server")
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
+	if err := shutdownTelemetry(shutdownCtx); err != nil {
+		log.Printf("Error shutting down telemetry: %v", err)
+	}
+	if exitErr != nil {
+		os.Exit(1)
+	}
+}
+
+// newHandler builds the routes of the service. The user routes answer 503
+// until the store is ready; the attribute rule watcher runs until ctx is
+// done.
+func newHandler(ctx context.Context, cfg Config, store *store, instr *instrument.Instrumenter) http.Handler {
+	var usersRouter http.Handler = users.NewTracedUserHandler(users.NewUserHandler(store.UserStore, instr))
+
+	// Apply declarative attribute rules, if configured
+	if rulesFile := env.String("ATTR_RULES_FILE", ""); rulesFile != "" {
+		rules, err := attrrules.Load(rulesFile)
+		if err != nil {
+			log.Fatalf("Failed to load attribute rules: %v", err)
+		}
+		engine := attrrules.NewEngine(rules)
+		usersRouter = engine.Middleware(usersRouter)
+		log.Printf("Loaded %d attribute rules (version %s) from %s", len(rules.Rules), rules.Version, rulesFile)
+
+		// Reload rules on file change or SIGHUP
+		interval, err := time.ParseDuration(env.String("ATTR_RULES_RELOAD_INTERVAL", "5s"))
+		if err != nil {
+			log.Fatalf("Invalid ATTR_RULES_RELOAD_INTERVAL: %v", err)
+		}
+		watcher, err := attrrules.NewWatcher(rulesFile, engine, interval)
+		if err != nil {
+			log.Fatalf("Failed to watch attribute rules: %v", err)
+		}
+		go watcher.Run(ctx)
+	}
+
+	// Setup routes
+	mux := http.NewServeMux()
+
+	// User routes
+	mux.Handle("/users/", requireReady(store, usersRouter))
+
+	// Telemetry diagnostics endpoint
+	mux.Handle("/debug/telemetry", telemetry.DiagnosticsHandler())
+
+	// Health check endpoint
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	// Readiness endpoint: 503 until the user store can serve requests
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if !store.ready.Load() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	if cfg.Docs != nil {
+		mux.Handle("/swagger/", cfg.Docs)
+	}
+
+	// Continue the caller's trace when no eBPF instrumentation owns server
+	// spans, and promote the gateway's baggage (tenant, customer tier) to
+	// span attributes
+	return telemetry.ServerMiddleware(telemetry.BaggageMiddleware(mux))
+}
+
+// requireReady answers 503 until the store is ready
+func requireReady(store *store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !store.ready.Load() {
+			http.Error(w, "service not ready", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"otelkit/instrument"
+)
+
+// get returns the status code of a GET request to path
+func get(t *testing.T, h http.Handler, path string) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code
+}
+
+func TestReadiness(t *testing.T) {
+	t.Setenv("STORE", "sqlite")
+	t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "users.db"))
+	t.Setenv("ATTR_RULES_FILE", "")
+
+	instr := instrument.New("test", instrument.ModeChildSpan)
+	store, err := openStore(instr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	h := newHandler(context.Background(), Config{Name: "test"}, store, instr)
+
+	// Liveness does not depend on the database; readiness and the user
+	// routes wait for it
+	for path, want := range map[string]int{"/health": http.StatusOK, "/ready": http.StatusServiceUnavailable, "/users/johndoe": http.StatusServiceUnavailable} {
+		if got := get(t, h, path); got != want {
+			t.Errorf("GET %s before start = %d, want %d", path, got, want)
+		}
+	}
+
+	if err := store.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The seed migration inserts johndoe
+	for path, want := range map[string]int{"/ready": http.StatusOK, "/users/johndoe": http.StatusOK} {
+		if got := get(t, h, path); got != want {
+			t.Errorf("GET %s after start = %d, want %d", path, got, want)
+		}
+	}
+}
+
+func TestOpenStore(t *testing.T) {
+	instr := instrument.New("test", instrument.ModeChildSpan)
+
+	t.Setenv("STORE", "memory")
+	store, err := openStore(instr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.migrateCommand(context.Background(), []string{"status"}); err == nil {
+		t.Error("migrate on the memory store succeeded")
+	}
+	if err := store.start(context.Background()); err != nil || !store.ready.Load() {
+		t.Errorf("start = %v, ready = %v; want the memory store ready", err, store.ready.Load())
+	}
+
+	t.Setenv("STORE", "mongodb")
+	if _, err := openStore(instr); err == nil {
+		t.Error("openStore accepted an unsupported STORE")
+	}
+}
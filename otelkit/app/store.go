@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+
+	"otelkit/env"
+	"otelkit/instrument"
+	"otelkit/migrate"
+	"otelkit/users"
+)
+
+// store is the user store selected by STORE: PostgreSQL by default,
+// STORE=sqlite for a local database file, STORE=memory runs without a
+// database
+type store struct {
+	users.UserStore
+	// description is logged on start
+	description string
+	// ready is set once the store can serve requests
+	ready atomic.Bool
+
+	// db, connect and migrator are nil for the memory store
+	db       *users.Database
+	connect  users.ConnectConfig
+	migrator *migrate.Migrator
+}
+
+// openStore opens the user store. The database is opened but not
+// connected; start connects it.
+func openStore(instr *instrument.Instrumenter) (*store, error) {
+	var db *users.Database
+	var err error
+	var description string
+
+	switch kind := env.String("STORE", "postgres"); kind {
+	case "memory":
+		return &store{UserStore: users.NewMemoryStore(instr), description: "User store: memory"}, nil
+	case "sqlite":
+		path := env.String("SQLITE_PATH", "users.db")
+		db, err = users.NewSQLiteDatabase(path)
+		description = "Database: sqlite " + path
+	case "postgres":
+		host := env.String("DB_HOST", "localhost")
+		port := env.String("DB_PORT", "5432")
+		user := env.String("DB_USER", "postgres")
+		name := env.String("DB_NAME", "postgres")
+		db, err = users.NewDatabase(host, port, user, env.String("DB_PASSWORD", "postgres"), name)
+		description = fmt.Sprintf("Database: %s@%s:%s/%s", user, host, port, name)
+	default:
+		return nil, fmt.Errorf("unsupported STORE %q", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	db.ConfigurePool(users.PoolConfigFromEnv())
+
+	// Load the versioned schema migrations
+	migrator, err := users.NewMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	return &store{
+		UserStore:   users.NewUserRepository(db, instr),
+		description: description,
+		db:          db,
+		connect:     users.ConnectConfigFromEnv(),
+		migrator:    migrator,
+	}, nil
+}
+
+// start waits until the database is reachable, retrying with backoff while
+// it starts, applies pending migrations unless MIGRATE_ON_START=false and
+// marks the store ready
+func (s *store) start(ctx context.Context) error {
+	if s.db != nil {
+		if err := s.db.Connect(ctx, s.connect); err != nil {
+			return err
+		}
+		if env.Bool("MIGRATE_ON_START", true) {
+			applied, err := s.migrator.Up(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to migrate schema: %w", err)
+			}
+			log.Printf("Database schema up to date (%d migrations applied)", applied)
+		}
+	}
+	s.ready.Store(true)
+	return nil
+}
+
+// migrateCommand connects to the database and runs the migrate command
+// with args
+func (s *store) migrateCommand(ctx context.Context, args []string) error {
+	if s.db == nil {
+		return errors.New("the migrate command requires STORE=postgres or STORE=sqlite")
+	}
+	if err := s.db.Connect(ctx, s.connect); err != nil {
+		return err
+	}
+	return migrate.RunCommand(ctx, s.migrator, args, os.Stdout)
+}
+
+// Close closes the database, if any
+func (s *store) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
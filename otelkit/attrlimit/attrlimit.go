@@ -0,0 +1,345 @@
+// Package attrlimit bounds what span attributes cost. A Limiter caps the
+// number of attributes per span, truncates long string and slice values,
+// and tracks the distinct values seen per key: once a key exceeds the
+// cardinality limit, its attributes are moved to a span event or dropped.
+//
+// Like redact, it runs as a span processor in front of the exporter when
+// the in-process SDK is used (NewProcessor) and as a SetAttributes hook
+// otherwise (Wrap). Affected spans are marked with the apm.limits.*
+// attributes and every action is counted in Stats and in the
+// apm.limits.actions counter of the global MeterProvider.
+package attrlimit
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// Action is what happens to the attributes of a high cardinality key
+type Action string
+
+// Cardinality actions
+const (
+	// ActionDemote moves the attribute to the DemotedEvent span event,
+	// which backends do not index
+	ActionDemote Action = "demote"
+	// ActionDrop removes the attribute
+	ActionDrop Action = "drop"
+)
+
+// ParseAction parses a cardinality action name
+func ParseAction(s string) (Action, error) {
+	switch action := Action(strings.ToLower(strings.TrimSpace(s))); action {
+	case ActionDemote, ActionDrop:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unsupported cardinality action %q", s)
+	}
+}
+
+// TruncationMarker is appended to truncated string values
+const TruncationMarker = "...[truncated]"
+
+// DemotedEvent is the name of the span event holding demoted attributes
+const DemotedEvent = "apm.limits.demoted"
+
+// maxTrackedKeys bounds the memory of the cardinality tracker; keys seen
+// after it is full are not tracked
+const maxTrackedKeys = 1024
+
+// Limits configures a Limiter; a zero field disables that limit
+type Limits struct {
+	// MaxAttributes is the number of attributes kept per span
+	MaxAttributes int
+	// MaxValueLength is the number of characters kept per string value and
+	// string slice element
+	MaxValueLength int
+	// MaxSliceLength is the number of elements kept per slice value
+	MaxSliceLength int
+	// MaxDistinctValues is the number of distinct values a key may have
+	// before it is treated as high cardinality
+	MaxDistinctValues int
+	// CardinalityAction is applied to high cardinality keys
+	CardinalityAction Action
+}
+
+// DefaultLimits returns the limits used when none are configured
+func DefaultLimits() Limits {
+	return Limits{
+		MaxAttributes:     64,
+		MaxValueLength:    256,
+		MaxSliceLength:    32,
+		MaxDistinctValues: 1000,
+		CardinalityAction: ActionDemote,
+	}
+}
+
+// Stats counts the actions a Limiter took
+type Stats struct {
+	Truncated           int64    `json:"truncated"`
+	Dropped             int64    `json:"dropped"`
+	Demoted             int64    `json:"demoted"`
+	HighCardinalityKeys []string `json:"high_cardinality_keys"`
+}
+
+// Actions reported as apm.limits.action on the apm.limits.actions counter
+var (
+	truncateAction = metric.WithAttributeSet(attribute.NewSet(apmattr.LimitsAction.String("truncate")))
+	dropAction     = metric.WithAttributeSet(attribute.NewSet(apmattr.LimitsAction.String(string(ActionDrop))))
+	demoteAction   = metric.WithAttributeSet(attribute.NewSet(apmattr.LimitsAction.String(string(ActionDemote))))
+)
+
+// Limiter applies Limits to span attributes
+type Limiter struct {
+	limits  Limits
+	tracker *tracker
+
+	truncated atomic.Int64
+	dropped   atomic.Int64
+	demoted   atomic.Int64
+	actions   metric.Int64Counter
+}
+
+// New creates a limiter
+func New(limits Limits) *Limiter {
+	if limits.CardinalityAction == "" {
+		limits.CardinalityAction = ActionDemote
+	}
+
+	actions, err := otel.Meter("otelkit/attrlimit").Int64Counter("apm.limits.actions",
+		metric.WithUnit("{attribute}"),
+		metric.WithDescription("Number of attributes truncated, dropped or demoted by the attribute limits"),
+	)
+	if err != nil {
+		otel.Handle(err)
+		actions = noop.Int64Counter{}
+	}
+
+	return &Limiter{limits: limits, tracker: newTracker(limits.MaxDistinctValues), actions: actions}
+}
+
+// count adds n attributes handled by an action to its Stats counter and to
+// the apm.limits.actions counter
+func (l *Limiter) count(stat *atomic.Int64, action metric.AddOption, n int64) {
+	if n == 0 {
+		return
+	}
+	stat.Add(n)
+	l.actions.Add(context.Background(), n, action)
+}
+
+// Stats returns a snapshot of the actions taken so far
+func (l *Limiter) Stats() Stats {
+	return Stats{
+		Truncated:           l.truncated.Load(),
+		Dropped:             l.dropped.Load(),
+		Demoted:             l.demoted.Load(),
+		HighCardinalityKeys: l.tracker.highCardinalityKeys(),
+	}
+}
+
+// result is the outcome of limiting a set of attributes
+type result struct {
+	attrs []attribute.KeyValue
+	// demoted are the high cardinality attributes to record as an event;
+	// empty when the cardinality action is drop
+	demoted []attribute.KeyValue
+	// demotedKeys are the high cardinality keys, demoted or dropped
+	demotedKeys []string
+	// truncatedKeys are the keys whose value was truncated
+	truncatedKeys []string
+}
+
+// limit applies the cardinality guard and the length limits to attrs.
+// attrs is not modified. The attribute count is limited by the caller,
+// which knows how many attributes the span already has.
+func (l *Limiter) limit(attrs []attribute.KeyValue) result {
+	var res result
+
+	for _, kv := range attrs {
+		if !marker(kv.Key) && l.tracker.observe(kv) {
+			res.demotedKeys = append(res.demotedKeys, string(kv.Key))
+			if l.limits.CardinalityAction == ActionDemote {
+				res.demoted = append(res.demoted, kv)
+				l.count(&l.demoted, demoteAction, 1)
+			} else {
+				l.count(&l.dropped, dropAction, 1)
+			}
+			continue
+		}
+
+		if value, ok := l.truncate(kv.Value); ok {
+			kv.Value = value
+			res.truncatedKeys = append(res.truncatedKeys, string(kv.Key))
+			l.count(&l.truncated, truncateAction, 1)
+		}
+		res.attrs = append(res.attrs, kv)
+	}
+
+	return res
+}
+
+// truncate applies the length limits to value; false means it is within them
+func (l *Limiter) truncate(value attribute.Value) (attribute.Value, bool) {
+	switch value.Type() {
+	case attribute.STRING:
+		s, ok := l.truncateString(value.AsString())
+		return attribute.StringValue(s), ok
+	case attribute.STRINGSLICE:
+		items, truncated := l.truncateSlice(len(value.AsStringSlice()))
+		out := value.AsStringSlice()[:items]
+		for i, item := range out {
+			var ok bool
+			if out[i], ok = l.truncateString(item); ok {
+				truncated = true
+			}
+		}
+		return attribute.StringSliceValue(out), truncated
+	case attribute.INT64SLICE:
+		items, truncated := l.truncateSlice(len(value.AsInt64Slice()))
+		return attribute.Int64SliceValue(value.AsInt64Slice()[:items]), truncated
+	case attribute.FLOAT64SLICE:
+		items, truncated := l.truncateSlice(len(value.AsFloat64Slice()))
+		return attribute.Float64SliceValue(value.AsFloat64Slice()[:items]), truncated
+	case attribute.BOOLSLICE:
+		items, truncated := l.truncateSlice(len(value.AsBoolSlice()))
+		return attribute.BoolSliceValue(value.AsBoolSlice()[:items]), truncated
+	default:
+		return value, false
+	}
+}
+
+// truncateString cuts s to MaxValueLength characters and appends the marker
+func (l *Limiter) truncateString(s string) (string, bool) {
+	n := l.limits.MaxValueLength
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s, false
+	}
+
+	i := 0
+	for ; n > 0; n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return s[:i] + TruncationMarker, true
+}
+
+// truncateSlice returns the number of elements to keep out of n
+func (l *Limiter) truncateSlice(n int) (int, bool) {
+	if max := l.limits.MaxSliceLength; max > 0 && n > max {
+		return max, true
+	}
+	return n, false
+}
+
+// marker reports whether key is one of the attributes marking processed
+// spans, which are never treated as high cardinality
+func marker(key attribute.Key) bool {
+	switch key {
+	case apmattr.RedactedKeys.Key(), apmattr.LimitsTruncatedKeys.Key(),
+		apmattr.LimitsDemotedKeys.Key(), apmattr.LimitsDroppedCount.Key():
+		return true
+	}
+	return false
+}
+
+// tracker counts the distinct values per key until a key exceeds the limit.
+// Values are kept as 64-bit hashes and a high cardinality key stops being
+// tracked, so memory is bounded by maxTrackedKeys times the limit.
+type tracker struct {
+	max int
+
+	mu     sync.Mutex
+	values map[attribute.Key]map[uint64]struct{}
+	high   map[attribute.Key]bool
+}
+
+// newTracker creates a tracker; max <= 0 disables it
+func newTracker(max int) *tracker {
+	return &tracker{
+		max:    max,
+		values: map[attribute.Key]map[uint64]struct{}{},
+		high:   map[attribute.Key]bool{},
+	}
+}
+
+// observe records the value of kv and reports whether its key is high
+// cardinality
+func (t *tracker) observe(kv attribute.KeyValue) bool {
+	if t.max <= 0 {
+		return false
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(kv.Value.Emit()))
+	sum := h.Sum64()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.high[kv.Key] {
+		return true
+	}
+
+	seen, ok := t.values[kv.Key]
+	if !ok {
+		if len(t.values) >= maxTrackedKeys {
+			return false
+		}
+		seen = map[uint64]struct{}{}
+		t.values[kv.Key] = seen
+	}
+
+	seen[sum] = struct{}{}
+	if len(seen) <= t.max {
+		return false
+	}
+
+	delete(t.values, kv.Key)
+	t.high[kv.Key] = true
+	return true
+}
+
+// highCardinalityKeys returns the sorted high cardinality keys
+func (t *tracker) highCardinalityKeys() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys := make([]string, 0, len(t.high))
+	for key := range t.high {
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// union returns the sorted union of a and b
+func union(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, k := range a {
+		seen[k] = true
+	}
+	for _, k := range b {
+		seen[k] = true
+	}
+
+	out := make([]string, 0, len(seen))
+	for k := range seen {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,177 @@
+package attrlimit
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestParseAction(t *testing.T) {
+	if got, err := ParseAction(" Drop "); err != nil || got != ActionDrop {
+		t.Errorf("ParseAction = %q, %v; want drop", got, err)
+	}
+	if _, err := ParseAction("ignore"); err == nil {
+		t.Error("ParseAction accepted an unsupported action")
+	}
+}
+
+func TestLimitTruncatesValues(t *testing.T) {
+	l := New(Limits{MaxValueLength: 4, MaxSliceLength: 2})
+
+	res := l.limit([]attribute.KeyValue{
+		attribute.String("apm.http.route", "/users"),
+		attribute.String("apm.user.tier", "gold"),
+		attribute.String("apm.user.username", "jöhndoe"),
+		attribute.StringSlice("apm.db.filters", []string{"name", "email", "age"}),
+		attribute.Int64Slice("apm.test.int64_slice", []int64{1, 2, 3}),
+		attribute.BoolSlice("apm.test.bool_slice", []bool{true}),
+		attribute.Int("apm.db.page_size", 123456),
+	})
+
+	want := []attribute.KeyValue{
+		attribute.String("apm.http.route", "/use"+TruncationMarker),
+		attribute.String("apm.user.tier", "gold"),
+		attribute.String("apm.user.username", "jöhn"+TruncationMarker),
+		attribute.StringSlice("apm.db.filters", []string{"name", "emai" + TruncationMarker}),
+		attribute.Int64Slice("apm.test.int64_slice", []int64{1, 2}),
+		attribute.BoolSlice("apm.test.bool_slice", []bool{true}),
+		attribute.Int("apm.db.page_size", 123456),
+	}
+	if !reflect.DeepEqual(res.attrs, want) {
+		t.Errorf("attrs = %v, want %v", res.attrs, want)
+	}
+
+	wantKeys := []string{"apm.http.route", "apm.user.username", "apm.db.filters", "apm.test.int64_slice"}
+	if !reflect.DeepEqual(res.truncatedKeys, wantKeys) {
+		t.Errorf("truncated keys = %v, want %v", res.truncatedKeys, wantKeys)
+	}
+	if got := l.Stats().Truncated; got != 4 {
+		t.Errorf("Stats().Truncated = %d, want 4", got)
+	}
+}
+
+func TestLimitCount(t *testing.T) {
+	l := New(Limits{MaxAttributes: 2})
+	set := map[attribute.Key]bool{}
+
+	attrs, dropped := l.limitCount([]attribute.KeyValue{
+		attribute.String("apm.http.route", "/users"),
+		attribute.String("apm.http.method", "GET"),
+		attribute.String("apm.user.tier", "gold"),
+	}, set)
+	if len(attrs) != 2 || dropped != 1 {
+		t.Errorf("first call kept %v, dropped %d; want 2 kept, 1 dropped", attrs, dropped)
+	}
+
+	// Existing keys can be overwritten and markers are not counted
+	attrs, dropped = l.limitCount([]attribute.KeyValue{
+		attribute.String("apm.http.route", "/users/{username}"),
+		attribute.StringSlice("apm.redacted.keys", []string{"apm.user.email"}),
+		attribute.String("apm.user.tier", "gold"),
+	}, set)
+	if len(attrs) != 2 || dropped != 1 {
+		t.Errorf("second call kept %v, dropped %d; want 2 kept, 1 dropped", attrs, dropped)
+	}
+
+	if got := l.Stats().Dropped; got != 2 {
+		t.Errorf("Stats().Dropped = %d, want 2", got)
+	}
+}
+
+func TestCardinalityGuard(t *testing.T) {
+	for _, action := range []Action{ActionDemote, ActionDrop} {
+		t.Run(string(action), func(t *testing.T) {
+			l := New(Limits{MaxDistinctValues: 3, CardinalityAction: action})
+
+			var demoted [][]attribute.KeyValue
+			for i := 0; i < 5; i++ {
+				res := l.limit([]attribute.KeyValue{
+					attribute.String("apm.custom.request.id", "req-"+strconv.Itoa(i)),
+					attribute.String("apm.http.method", "GET"),
+				})
+				if len(res.demotedKeys) > 0 {
+					demoted = append(demoted, res.demoted)
+				}
+				for _, kv := range res.attrs {
+					if kv.Key == "apm.custom.request.id" && i >= 3 {
+						t.Errorf("request %d kept the high cardinality attribute", i)
+					}
+				}
+			}
+
+			if len(demoted) != 2 {
+				t.Fatalf("demoted on %d requests, want 2", len(demoted))
+			}
+			if action == ActionDemote && (len(demoted[1]) != 1 || demoted[1][0].Value.AsString() != "req-4") {
+				t.Errorf("demoted = %v, want the request id", demoted[1])
+			}
+			if action == ActionDrop && demoted[1] != nil {
+				t.Errorf("dropped attributes were demoted: %v", demoted[1])
+			}
+
+			stats := l.Stats()
+			if strings.Join(stats.HighCardinalityKeys, ",") != "apm.custom.request.id" {
+				t.Errorf("HighCardinalityKeys = %v", stats.HighCardinalityKeys)
+			}
+			if stats.Demoted+stats.Dropped != 2 {
+				t.Errorf("Stats = %+v, want 2 demoted or dropped", stats)
+			}
+		})
+	}
+}
+
+func TestCardinalityGuardRepeatedValues(t *testing.T) {
+	l := New(Limits{MaxDistinctValues: 2})
+
+	for i := 0; i < 100; i++ {
+		res := l.limit([]attribute.KeyValue{attribute.String("apm.user.tier", []string{"gold", "silver"}[i%2])})
+		if len(res.demotedKeys) > 0 {
+			t.Fatalf("low cardinality key demoted after %d values", i)
+		}
+	}
+}
+
+func TestActionsCounter(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	l := New(Limits{MaxAttributes: 1, MaxValueLength: 4, MaxDistinctValues: 1})
+	res := l.limit([]attribute.KeyValue{attribute.String("apm.http.route", "/users")})
+	l.limit([]attribute.KeyValue{attribute.String("apm.http.route", "/health")})
+	l.limitCount(append(res.attrs, attribute.String("apm.user.tier", "gold")), map[attribute.Key]bool{})
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "apm.limits.actions" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				action, _ := dp.Attributes.Value(apmattr.LimitsAction.Key())
+				got[action.AsString()] = dp.Value
+			}
+		}
+	}
+
+	want := map[string]int64{"truncate": 1, "demote": 1, "drop": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("apm.limits.actions = %v, want %v", got, want)
+	}
+	if stats := l.Stats(); stats.Truncated != 1 || stats.Demoted != 1 || stats.Dropped != 1 {
+		t.Errorf("Stats = %+v, want one of each action", stats)
+	}
+}
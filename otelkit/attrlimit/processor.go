@@ -0,0 +1,218 @@
+package attrlimit
+
+import (
+	"context"
+	"sync"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// limitCount keeps the attributes that fit in the per-span attribute limit
+// and returns how many were dropped. set holds the keys the span already
+// has and is updated; overwriting one of them is always allowed, and the
+// marker attributes are not counted.
+func (l *Limiter) limitCount(attrs []attribute.KeyValue, set map[attribute.Key]bool) ([]attribute.KeyValue, int) {
+	max := l.limits.MaxAttributes
+	if max <= 0 {
+		return attrs, 0
+	}
+
+	out := attrs[:0:0]
+	dropped := 0
+	for _, kv := range attrs {
+		if !marker(kv.Key) && !set[kv.Key] {
+			if len(set) >= max {
+				dropped++
+				continue
+			}
+			set[kv.Key] = true
+		}
+		out = append(out, kv)
+	}
+
+	l.count(&l.dropped, dropAction, int64(dropped))
+	return out, dropped
+}
+
+// marks accumulates what was done to a span's attributes
+type marks struct {
+	truncated []string
+	demoted   []string
+	dropped   int
+}
+
+// add records an action and reports whether anything was recorded
+func (m *marks) add(res result, dropped int) bool {
+	if len(res.truncatedKeys) == 0 && len(res.demotedKeys) == 0 && dropped == 0 {
+		return false
+	}
+	m.truncated = union(m.truncated, res.truncatedKeys)
+	m.demoted = union(m.demoted, res.demotedKeys)
+	m.dropped += dropped
+	return true
+}
+
+// attributes returns the marker attributes for the recorded actions
+func (m *marks) attributes() []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if len(m.truncated) > 0 {
+		attrs = append(attrs, apmattr.LimitsTruncatedKeys.StringSlice(m.truncated))
+	}
+	if len(m.demoted) > 0 {
+		attrs = append(attrs, apmattr.LimitsDemotedKeys.StringSlice(m.demoted))
+	}
+	if m.dropped > 0 {
+		attrs = append(attrs, apmattr.LimitsDroppedCount.Int(m.dropped))
+	}
+	return attrs
+}
+
+// processor limits ended spans before handing them to the next processor
+type processor struct {
+	next    sdktrace.SpanProcessor
+	limiter *Limiter
+}
+
+// NewProcessor returns a span processor that applies the limiter to the
+// attributes of ended spans, then passes them to next. Event attributes
+// are truncated but not counted or tracked.
+func NewProcessor(next sdktrace.SpanProcessor, limiter *Limiter) sdktrace.SpanProcessor {
+	return &processor{next: next, limiter: limiter}
+}
+
+// OnStart passes the started span to the next processor
+func (p *processor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(ctx, s)
+}
+
+// OnEnd limits the span and passes it to the next processor
+func (p *processor) OnEnd(s sdktrace.ReadOnlySpan) {
+	res := p.limiter.limit(s.Attributes())
+	attrs, dropped := p.limiter.limitCount(res.attrs, map[attribute.Key]bool{})
+
+	events := s.Events()
+	var limitedEvents []sdktrace.Event
+	for i, e := range events {
+		eventRes := result{attrs: e.Attributes}
+		for j, kv := range e.Attributes {
+			value, ok := p.limiter.truncate(kv.Value)
+			if !ok {
+				continue
+			}
+			if eventRes.truncatedKeys == nil {
+				eventRes.attrs = append([]attribute.KeyValue(nil), e.Attributes...)
+			}
+			eventRes.attrs[j].Value = value
+			eventRes.truncatedKeys = append(eventRes.truncatedKeys, string(kv.Key))
+			p.limiter.count(&p.limiter.truncated, truncateAction, 1)
+		}
+		if eventRes.truncatedKeys == nil {
+			continue
+		}
+		if limitedEvents == nil {
+			limitedEvents = append([]sdktrace.Event(nil), events...)
+		}
+		limitedEvents[i].Attributes = eventRes.attrs
+		res.truncatedKeys = append(res.truncatedKeys, eventRes.truncatedKeys...)
+	}
+
+	var m marks
+	if !m.add(res, dropped) {
+		p.next.OnEnd(s)
+		return
+	}
+	if limitedEvents == nil {
+		limitedEvents = events
+	}
+	if len(res.demoted) > 0 {
+		limitedEvents = append(limitedEvents[:len(limitedEvents):len(limitedEvents)], sdktrace.Event{
+			Name:       DemotedEvent,
+			Attributes: res.demoted,
+			Time:       s.EndTime(),
+		})
+	}
+
+	p.next.OnEnd(&limitedSpan{
+		ReadOnlySpan: s,
+		attrs:        append(attrs, m.attributes()...),
+		events:       limitedEvents,
+		dropped:      dropped,
+	})
+}
+
+// Shutdown shuts the next processor down
+func (p *processor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush flushes the next processor
+func (p *processor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// limitedSpan is an ended span with limited attributes and events
+type limitedSpan struct {
+	sdktrace.ReadOnlySpan
+	attrs   []attribute.KeyValue
+	events  []sdktrace.Event
+	dropped int
+}
+
+// Attributes returns the limited span attributes
+func (s *limitedSpan) Attributes() []attribute.KeyValue {
+	return s.attrs
+}
+
+// Events returns the events with truncated attributes and the demoted event
+func (s *limitedSpan) Events() []sdktrace.Event {
+	return s.events
+}
+
+// DroppedAttributes adds the attributes dropped by the limiter to those
+// dropped by the SDK
+func (s *limitedSpan) DroppedAttributes() int {
+	return s.ReadOnlySpan.DroppedAttributes() + s.dropped
+}
+
+// Wrap returns span with a SetAttributes that applies the limiter before
+// attributes are set. It is the hook for spans that are not exported
+// through an SDK processor; the attribute limit counts the attributes set
+// through the returned span only.
+func Wrap(span trace.Span, limiter *Limiter) trace.Span {
+	if limiter == nil {
+		return span
+	}
+	return &limitingSpan{Span: span, limiter: limiter, set: map[attribute.Key]bool{}}
+}
+
+// limitingSpan limits attributes before setting them on the wrapped span
+type limitingSpan struct {
+	trace.Span
+	limiter *Limiter
+
+	mu    sync.Mutex
+	set   map[attribute.Key]bool
+	marks marks
+}
+
+// SetAttributes limits kv, sets it and updates the apm.limits.* markers;
+// demoted attributes are added as a DemotedEvent event
+func (s *limitingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	res := s.limiter.limit(kv)
+
+	s.mu.Lock()
+	attrs, dropped := s.limiter.limitCount(res.attrs, s.set)
+	if s.marks.add(res, dropped) {
+		attrs = append(attrs, s.marks.attributes()...)
+	}
+	s.mu.Unlock()
+
+	if len(res.demoted) > 0 {
+		s.Span.AddEvent(DemotedEvent, trace.WithAttributes(res.demoted...))
+	}
+	s.Span.SetAttributes(attrs...)
+}
@@ -0,0 +1,125 @@
+package attrlimit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// attrs indexes the attributes of a span by key
+func attrs(kvs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value, len(kvs))
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestProcessor(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	l := New(Limits{MaxAttributes: 3, MaxValueLength: 10, MaxDistinctValues: 1})
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(NewProcessor(sdktrace.NewSimpleSpanProcessor(exporter), l)))
+
+	for _, id := range []string{"a", "b"} {
+		_, span := tp.Tracer("test").Start(context.Background(), "request")
+		span.SetAttributes(
+			attribute.String("apm.custom.request.id", id),
+			attribute.String("apm.db.statement", "SELECT id, username, email FROM users"),
+			attribute.String("apm.http.method", "GET"),
+			attribute.String("apm.http.route", "/users"),
+			attribute.String("apm.user.tier", "gold"),
+		)
+		span.AddEvent("query", trace.WithAttributes(attribute.String("apm.db.statement", "SELECT * FROM users")))
+		span.End()
+	}
+
+	spans := exporter.GetSpans()
+	first, second := attrs(spans[0].Attributes), attrs(spans[1].Attributes)
+
+	if first["apm.custom.request.id"].AsString() != "a" {
+		t.Errorf("first span lost the request id: %v", first)
+	}
+	if _, ok := second["apm.custom.request.id"]; ok {
+		t.Error("second span kept the high cardinality request id")
+	}
+	if got := second["apm.limits.demoted.keys"].AsStringSlice(); strings.Join(got, ",") != "apm.custom.request.id" {
+		t.Errorf("apm.limits.demoted.keys = %v", got)
+	}
+
+	events := spans[1].Events
+	if last := events[len(events)-1]; last.Name != DemotedEvent || attrs(last.Attributes)["apm.custom.request.id"].AsString() != "b" {
+		t.Errorf("last event = %+v, want the demoted request id", last)
+	}
+	if got := attrs(events[0].Attributes)["apm.db.statement"].AsString(); got != "SELECT * F"+TruncationMarker {
+		t.Errorf("event statement = %q, want it truncated", got)
+	}
+
+	if got := second["apm.db.statement"].AsString(); got != "SELECT id,"+TruncationMarker {
+		t.Errorf("apm.db.statement = %q, want it truncated", got)
+	}
+	if got := second["apm.limits.truncated.keys"].AsStringSlice(); strings.Join(got, ",") != "apm.db.statement" {
+		t.Errorf("apm.limits.truncated.keys = %v", got)
+	}
+
+	if _, ok := second["apm.user.tier"]; ok || second["apm.limits.dropped.count"].AsInt64() != 1 {
+		t.Errorf("attributes = %v, want apm.user.tier dropped by the count limit", second)
+	}
+	if spans[1].DroppedAttributes != 1 {
+		t.Errorf("DroppedAttributes = %d, want 1", spans[1].DroppedAttributes)
+	}
+}
+
+func TestProcessorLeavesSpansWithinLimits(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(NewProcessor(sdktrace.NewSimpleSpanProcessor(exporter), New(DefaultLimits()))))
+
+	_, span := tp.Tracer("test").Start(context.Background(), "request")
+	span.SetAttributes(attribute.String("apm.http.route", "/users"))
+	span.End()
+
+	if got := exporter.GetSpans()[0].Attributes; len(got) != 1 {
+		t.Errorf("attributes = %v, want only apm.http.route", got)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	l := New(Limits{MaxAttributes: 2, MaxValueLength: 3, MaxDistinctValues: 1})
+
+	// The first value of apm.custom.request.id is within the limit
+	_, warm := tp.Tracer("test").Start(context.Background(), "warm")
+	Wrap(warm, l).SetAttributes(attribute.String("apm.custom.request.id", "a"))
+	warm.End()
+
+	_, span := tp.Tracer("test").Start(context.Background(), "request")
+	wrapped := Wrap(span, l)
+	wrapped.SetAttributes(attribute.String("apm.custom.request.id", "b"), attribute.String("apm.http.route", "/users"))
+	wrapped.SetAttributes(attribute.String("apm.http.method", "GET"), attribute.String("apm.user.tier", "gold"))
+	wrapped.End()
+
+	ended := recorder.Ended()[1]
+	got := attrs(ended.Attributes())
+
+	if got["apm.http.route"].AsString() != "/us"+TruncationMarker {
+		t.Errorf("apm.http.route = %q, want it truncated", got["apm.http.route"].AsString())
+	}
+	if _, ok := got["apm.user.tier"]; ok || got["apm.limits.dropped.count"].AsInt64() != 1 {
+		t.Errorf("attributes = %v, want apm.user.tier dropped by the count limit", got)
+	}
+	if strings.Join(got["apm.limits.demoted.keys"].AsStringSlice(), ",") != "apm.custom.request.id" {
+		t.Errorf("apm.limits.demoted.keys = %v", got["apm.limits.demoted.keys"].AsStringSlice())
+	}
+	if events := ended.Events(); len(events) != 1 || events[0].Name != DemotedEvent {
+		t.Errorf("events = %+v, want the demoted event", events)
+	}
+
+	if Wrap(span, nil) != span {
+		t.Error("Wrap with a nil limiter did not return the span")
+	}
+}
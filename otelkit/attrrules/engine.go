@@ -0,0 +1,333 @@
+package attrrules
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"otelkit/apmattr"
+	"otelkit/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxBodyBytes bounds how much of the request body is buffered for body rules
+const maxBodyBytes = 1 << 20
+
+// Engine evaluates a rule set against incoming requests
+type Engine struct {
+	rules  atomic.Pointer[RuleSet]
+	tracer trace.Tracer
+}
+
+// NewEngine creates a new rule engine for the given rule set
+func NewEngine(rules *RuleSet) *Engine {
+	e := &Engine{tracer: otel.Tracer("attrrules")}
+	e.rules.Store(rules)
+	return e
+}
+
+// RuleSet returns the rule set currently in effect
+func (e *Engine) RuleSet() *RuleSet {
+	return e.rules.Load()
+}
+
+// Swap atomically replaces the rule set. Requests already being processed
+// keep the rule set they started with; new requests use the new one.
+func (e *Engine) Swap(rules *RuleSet) *RuleSet {
+	return e.rules.Swap(rules)
+}
+
+// Middleware applies the rule set to the active or a newly started span
+// before delegating to next
+func (e *Engine) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rules := e.rules.Load()
+		attrs := e.evaluate(rules, r)
+		if rules.Version != "" {
+			attrs = append(attrs, apmattr.RulesVersion.String(rules.Version))
+		}
+
+		// Rule attributes can carry personal data and unbounded values, so
+		// they go through the span hooks when spans are exported outside
+		// the process
+		if rules.Target == TargetNew {
+			ctx, span := e.tracer.Start(r.Context(), rules.SpanName)
+			defer span.End()
+			telemetry.WrapSpan(span).SetAttributes(attrs...)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		telemetry.WrapSpan(trace.SpanFromContext(r.Context())).SetAttributes(attrs...)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Attributes returns the attributes the rule set produces for the request
+func (e *Engine) Attributes(r *http.Request) []attribute.KeyValue {
+	return e.evaluate(e.rules.Load(), r)
+}
+
+// evaluate runs every matching rule and converts the extracted values
+func (e *Engine) evaluate(rules *RuleSet, r *http.Request) []attribute.KeyValue {
+	route, pathValues := resolveRoute(rules.Routes, r.URL.Path)
+
+	var body map[string]interface{}
+	bodyRead := false
+
+	attrs := make([]attribute.KeyValue, 0, len(rules.Rules))
+	for _, rule := range rules.Rules {
+		if !rule.matches(r.Method, route) {
+			continue
+		}
+
+		var raw string
+		var ok bool
+
+		switch rule.From {
+		case SourceRoute:
+			raw, ok = route, route != ""
+		case SourceMethod:
+			raw, ok = r.Method, true
+		case SourceHeader:
+			raw = r.Header.Get(rule.Key)
+			ok = raw != ""
+		case SourceQuery:
+			ok = r.URL.Query().Has(rule.Key)
+			raw = r.URL.Query().Get(rule.Key)
+		case SourcePath:
+			raw, ok = pathValues[rule.Key]
+			if !ok {
+				raw = r.PathValue(rule.Key)
+				ok = raw != ""
+			}
+		case SourceBody:
+			if !bodyRead {
+				body = readJSONBody(r)
+				bodyRead = true
+			}
+			raw, ok = lookupField(body, rule.Key)
+		case SourceStatic:
+			raw, ok = rule.Value, true
+		}
+
+		if !ok {
+			if rule.Default == "" {
+				continue
+			}
+			raw = rule.Default
+		}
+
+		if kv, ok := convert(rule.Attribute, rule.Type, raw); ok {
+			attrs = append(attrs, kv)
+		}
+	}
+
+	return attrs
+}
+
+// matches reports whether the rule applies to the method and route
+func (r *Rule) matches(method, route string) bool {
+	if len(r.Methods) > 0 && !contains(r.Methods, method) {
+		return false
+	}
+	if len(r.Routes) > 0 && !contains(r.Routes, route) {
+		return false
+	}
+	return true
+}
+
+// resolveRoute finds the first route template matching the path and
+// returns it together with the captured {name} path values
+func resolveRoute(templates []string, path string) (string, map[string]string) {
+	segments := splitPath(path)
+
+	for _, tmpl := range templates {
+		parts := splitPath(tmpl)
+		if len(parts) != len(segments) {
+			continue
+		}
+
+		values := map[string]string{}
+		matched := true
+		for i, part := range parts {
+			if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+				values[strings.Trim(part, "{}")] = segments[i]
+				continue
+			}
+			if part != segments[i] {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return tmpl, values
+		}
+	}
+
+	return "", nil
+}
+
+// splitPath splits a URL path into its non-empty segments
+func splitPath(path string) []string {
+	trimmed := strings.Trim(path, "/")
+	if trimmed == "" {
+		return nil
+	}
+	return strings.Split(trimmed, "/")
+}
+
+// readJSONBody decodes the request body as a JSON object and restores it
+// so the downstream handler can still read it
+func readJSONBody(r *http.Request) map[string]interface{} {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	// Put the buffered prefix back in front of the unread remainder, so
+	// bodies larger than maxBodyBytes still reach the handler intact. The
+	// server closes the original body.
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), r.Body))
+	if err != nil {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var body map[string]interface{}
+	if err := dec.Decode(&body); err != nil {
+		return nil
+	}
+
+	return body
+}
+
+// lookupField resolves a dot separated field path in a decoded JSON object
+func lookupField(body map[string]interface{}, key string) (string, bool) {
+	var current interface{} = body
+	for _, part := range strings.Split(key, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		current, ok = obj[part]
+		if !ok {
+			return "", false
+		}
+	}
+
+	return stringify(current)
+}
+
+// stringify renders a decoded JSON value in the textual form used by convert
+func stringify(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return val, true
+	case json.Number:
+		return val.String(), true
+	case bool:
+		return strconv.FormatBool(val), true
+	case []interface{}:
+		items := make([]string, 0, len(val))
+		for _, item := range val {
+			s, ok := stringify(item)
+			if !ok {
+				return "", false
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), true
+	default:
+		return "", false
+	}
+}
+
+// convert builds a typed attribute from its textual value
+func convert(key, typ, raw string) (attribute.KeyValue, bool) {
+	switch typ {
+	case TypeString:
+		return attribute.String(key, raw), true
+	case TypeInt:
+		v, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		return attribute.Int64(key, v), err == nil
+	case TypeFloat:
+		v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		return attribute.Float64(key, v), err == nil
+	case TypeBool:
+		v, err := strconv.ParseBool(strings.TrimSpace(raw))
+		return attribute.Bool(key, v), err == nil
+	case TypeStringSlice:
+		return attribute.StringSlice(key, splitList(raw)), true
+	case TypeIntSlice:
+		items := splitList(raw)
+		values := make([]int64, len(items))
+		for i, item := range items {
+			v, err := strconv.ParseInt(item, 10, 64)
+			if err != nil {
+				return attribute.KeyValue{}, false
+			}
+			values[i] = v
+		}
+		return attribute.Int64Slice(key, values), true
+	case TypeFloatSlice:
+		items := splitList(raw)
+		values := make([]float64, len(items))
+		for i, item := range items {
+			v, err := strconv.ParseFloat(item, 64)
+			if err != nil {
+				return attribute.KeyValue{}, false
+			}
+			values[i] = v
+		}
+		return attribute.Float64Slice(key, values), true
+	case TypeBoolSlice:
+		items := splitList(raw)
+		values := make([]bool, len(items))
+		for i, item := range items {
+			v, err := strconv.ParseBool(item)
+			if err != nil {
+				return attribute.KeyValue{}, false
+			}
+			values[i] = v
+		}
+		return attribute.BoolSlice(key, values), true
+	}
+
+	return attribute.KeyValue{}, false
+}
+
+// splitList splits a comma separated list, trimming whitespace around items
+func splitList(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return []string{}
+	}
+
+	items := strings.Split(raw, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package attrrules
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestResolveRoute(t *testing.T) {
+	templates := []string{"/users", "/users/{username}", "/users/{username}/orders/{id}"}
+
+	tests := []struct {
+		path   string
+		route  string
+		values map[string]string
+	}{
+		{"/users", "/users", map[string]string{}},
+		{"/users/", "/users", map[string]string{}},
+		{"/users/johndoe", "/users/{username}", map[string]string{"username": "johndoe"}},
+		{"/users/johndoe/orders/42", "/users/{username}/orders/{id}", map[string]string{"username": "johndoe", "id": "42"}},
+		{"/users/johndoe/carts/42", "", nil},
+		{"/health", "", nil},
+		{"/", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			route, values := resolveRoute(templates, tt.path)
+			if route != tt.route {
+				t.Errorf("route = %q, want %q", route, tt.route)
+			}
+			if !reflect.DeepEqual(values, tt.values) {
+				t.Errorf("values = %v, want %v", values, tt.values)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		typ  string
+		raw  string
+		want attribute.Value
+		ok   bool
+	}{
+		{TypeString, "gold", attribute.StringValue("gold"), true},
+		{TypeInt, " 42 ", attribute.Int64Value(42), true},
+		{TypeInt, "forty", attribute.Value{}, false},
+		{TypeFloat, "1.5", attribute.Float64Value(1.5), true},
+		{TypeFloat, "x", attribute.Value{}, false},
+		{TypeBool, "true", attribute.BoolValue(true), true},
+		{TypeBool, "yes", attribute.Value{}, false},
+		{TypeStringSlice, "a, b ,c", attribute.StringSliceValue([]string{"a", "b", "c"}), true},
+		{TypeStringSlice, "", attribute.StringSliceValue([]string{}), true},
+		{TypeIntSlice, "1,2,3", attribute.Int64SliceValue([]int64{1, 2, 3}), true},
+		{TypeIntSlice, "1,two", attribute.Value{}, false},
+		{TypeFloatSlice, "1.5, 2", attribute.Float64SliceValue([]float64{1.5, 2}), true},
+		{TypeFloatSlice, "1.5,x", attribute.Value{}, false},
+		{TypeBoolSlice, "true,false", attribute.BoolSliceValue([]bool{true, false}), true},
+		{TypeBoolSlice, "true,maybe", attribute.Value{}, false},
+		{"uuid", "x", attribute.Value{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ+"/"+tt.raw, func(t *testing.T) {
+			kv, ok := convert("apm.test", tt.typ, tt.raw)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if kv.Key != "apm.test" {
+				t.Errorf("key = %q, want apm.test", kv.Key)
+			}
+			if kv.Value.Emit() != tt.want.Emit() {
+				t.Errorf("value = %s, want %s", kv.Value.Emit(), tt.want.Emit())
+			}
+			if kv.Value.Type() != tt.want.Type() {
+				t.Errorf("type = %s, want %s", kv.Value.Type(), tt.want.Type())
+			}
+		})
+	}
+}
+
+func TestLookupField(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`{
+		"age": 30,
+		"name": "John",
+		"active": true,
+		"nothing": null,
+		"tags": ["a", "b"],
+		"mixed": ["a", {"b": 1}],
+		"address": {"city": "Pune", "zip": {"code": 411001}}
+	}`))
+	dec.UseNumber()
+	var body map[string]interface{}
+	if err := dec.Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{"age", "30", true},
+		{"name", "John", true},
+		{"active", "true", true},
+		{"tags", "a,b", true},
+		{"address.city", "Pune", true},
+		{"address.zip.code", "411001", true},
+		{"nothing", "", false},
+		{"mixed", "", false},
+		{"address", "", false},
+		{"missing", "", false},
+		{"name.first", "", false},
+		{"address.street", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := lookupField(body, tt.key)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("lookupField(%q) = %q, %v; want %q, %v", tt.key, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	if _, ok := lookupField(nil, "age"); ok {
+		t.Error("lookupField on a nil body succeeded")
+	}
+}
+
+func TestReadJSONBodyRestoresBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantBody bool
+	}{
+		{"object", `{"age": 30}`, true},
+		{"invalid json", `{"age":`, false},
+		{"larger than the buffer", `{"name": "` + strings.Repeat("x", maxBodyBytes) + `"}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+
+			body := readJSONBody(r)
+			if (body != nil) != tt.wantBody {
+				t.Errorf("decoded body = %v, want decoded %v", body, tt.wantBody)
+			}
+
+			rest, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(rest, []byte(tt.body)) {
+				t.Errorf("handler read %d bytes, want the full %d byte body", len(rest), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestEngineAttributes(t *testing.T) {
+	rules, err := Parse([]byte(`
+version: "1"
+routes: ["/users", "/users/{username}"]
+rules:
+  - attribute: apm.http.route
+    from: route
+  - attribute: apm.user.username
+    from: path
+    key: username
+  - attribute: apm.user.tier
+    from: header
+    key: X-User-Tier
+    default: standard
+  - attribute: apm.user.age
+    type: int
+    from: body
+    key: age
+    methods: [POST]
+`), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/users/johndoe", strings.NewReader(`{"age": 30}`))
+	got := NewEngine(rules).Attributes(r)
+
+	want := []attribute.KeyValue{
+		attribute.String("apm.http.route", "/users/{username}"),
+		attribute.String("apm.user.username", "johndoe"),
+		attribute.String("apm.user.tier", "standard"),
+		attribute.Int64("apm.user.age", 30),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Attributes() = %v, want %v", got, want)
+	}
+}
+
+func TestParseRejectsUncataloguedAttributes(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{"unknown key", "rules:\n  - attribute: apm.user.shoe_size\n    from: method\n"},
+		{"wrong type", "rules:\n  - attribute: apm.user.age\n    type: string\n    from: method\n"},
+		{"misspelled prefix", "rules:\n  - attribute: sapm.uccess\n    type: bool\n    from: static\n    value: \"true\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.rules), "yaml"); err == nil {
+				t.Error("Parse succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// Package attrrules implements declarative, rule-based custom attribute injection.
+//
+// A rule set maps request features (route, method, header, query parameter,
+// JSON body field, path value or a static value) to typed span attributes.
+// Rule sets are loaded from YAML or JSON files and applied by an HTTP
+// middleware to the active span or to a newly started span.
+package attrrules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/yaml.v3"
+)
+
+// Span targets
+const (
+	// TargetActive enriches the span already present in the request context
+	TargetActive = "active"
+	// TargetNew starts a new child span and enriches it
+	TargetNew = "new"
+)
+
+// Request feature sources
+const (
+	SourceRoute  = "route"
+	SourceMethod = "method"
+	SourceHeader = "header"
+	SourceQuery  = "query"
+	SourceBody   = "body"
+	SourcePath   = "path"
+	SourceStatic = "static"
+)
+
+// Attribute value types
+const (
+	TypeString      = "string"
+	TypeInt         = "int"
+	TypeFloat       = "float"
+	TypeBool        = "bool"
+	TypeStringSlice = "string[]"
+	TypeIntSlice    = "int[]"
+	TypeFloatSlice  = "float[]"
+	TypeBoolSlice   = "bool[]"
+)
+
+// attributeTypes maps rule types to the attribute types they produce
+var attributeTypes = map[string]attribute.Type{
+	TypeString:      attribute.STRING,
+	TypeInt:         attribute.INT64,
+	TypeFloat:       attribute.FLOAT64,
+	TypeBool:        attribute.BOOL,
+	TypeStringSlice: attribute.STRINGSLICE,
+	TypeIntSlice:    attribute.INT64SLICE,
+	TypeFloatSlice:  attribute.FLOAT64SLICE,
+	TypeBoolSlice:   attribute.BOOLSLICE,
+}
+
+// RuleSet is a declarative set of attribute injection rules
+type RuleSet struct {
+	// Version identifies the rule set, defaults to a hash of the file contents
+	Version string `json:"version" yaml:"version"`
+	// Target selects the span the rules are applied to: "active" (default) or "new"
+	Target string `json:"target" yaml:"target"`
+	// SpanName is the name of the span started when Target is "new"
+	SpanName string `json:"span_name" yaml:"span_name"`
+	// Routes are the route templates used to resolve {name} path values
+	Routes []string `json:"routes" yaml:"routes"`
+	// Rules are evaluated in order for every request
+	Rules []Rule `json:"rules" yaml:"rules"`
+
+	// digest is the hash of the file contents the rule set was parsed from
+	digest string
+}
+
+// Rule maps a single request feature to a span attribute
+type Rule struct {
+	// Attribute is the span attribute key, e.g. apm.user.tier
+	Attribute string `json:"attribute" yaml:"attribute"`
+	// Type is the attribute value type, defaults to "string"
+	Type string `json:"type" yaml:"type"`
+	// From is the request feature the value is read from
+	From string `json:"from" yaml:"from"`
+	// Key names the header, query parameter, JSON body field (dot separated) or path value
+	Key string `json:"key" yaml:"key"`
+	// Value is the literal value for the "static" source
+	Value string `json:"value" yaml:"value"`
+	// Default is used when the feature is absent from the request
+	Default string `json:"default" yaml:"default"`
+	// Methods restricts the rule to the given HTTP methods
+	Methods []string `json:"methods" yaml:"methods"`
+	// Routes restricts the rule to the given route templates
+	Routes []string `json:"routes" yaml:"routes"`
+}
+
+// Load reads a rule set from a YAML (.yaml, .yml) or JSON (.json) file
+func Load(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rules file: %w", err)
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	return Parse(data, format)
+}
+
+// Parse decodes and validates a rule set in the given format ("yaml", "yml" or "json")
+func Parse(data []byte, format string) (*RuleSet, error) {
+	rs := &RuleSet{}
+
+	switch format {
+	case "yaml", "yml":
+		if err := yaml.Unmarshal(data, rs); err != nil {
+			return nil, fmt.Errorf("error parsing yaml rules: %w", err)
+		}
+	case "json":
+		if err := json.Unmarshal(data, rs); err != nil {
+			return nil, fmt.Errorf("error parsing json rules: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported rules format %q", format)
+	}
+
+	if err := rs.Validate(); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	rs.digest = hex.EncodeToString(sum[:])
+	if rs.Version == "" {
+		rs.Version = rs.digest[:12]
+	}
+
+	return rs, nil
+}
+
+// Validate checks the rule set and fills in defaults
+func (rs *RuleSet) Validate() error {
+	switch rs.Target {
+	case "":
+		rs.Target = TargetActive
+	case TargetActive, TargetNew:
+	default:
+		return fmt.Errorf("invalid target %q", rs.Target)
+	}
+
+	if rs.Target == TargetNew && rs.SpanName == "" {
+		rs.SpanName = "attribute_rules"
+	}
+
+	for i := range rs.Rules {
+		if err := rs.Rules[i].validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validate checks a single rule and fills in defaults
+func (r *Rule) validate() error {
+	if strings.TrimSpace(r.Attribute) == "" {
+		return fmt.Errorf("attribute is required")
+	}
+
+	if r.Type == "" {
+		r.Type = TypeString
+	}
+
+	typ, ok := attributeTypes[r.Type]
+	if !ok {
+		return fmt.Errorf("attribute %s: unsupported type %q", r.Attribute, r.Type)
+	}
+
+	// Rule attributes must be catalogued like the ones set in code
+	def, ok := apmattr.Lookup(r.Attribute)
+	if !ok {
+		return fmt.Errorf("attribute %s is not declared in the apmattr catalogue", r.Attribute)
+	}
+	if def.Type != typ {
+		return fmt.Errorf("attribute %s: type %q does not match the catalogued type %s", r.Attribute, r.Type, def.Type)
+	}
+
+	switch r.From {
+	case SourceRoute, SourceMethod:
+	case SourceHeader, SourceQuery, SourceBody, SourcePath:
+		if r.Key == "" {
+			return fmt.Errorf("attribute %s: key is required for source %q", r.Attribute, r.From)
+		}
+	case SourceStatic:
+		if r.Value == "" {
+			return fmt.Errorf("attribute %s: value is required for source %q", r.Attribute, r.From)
+		}
+	default:
+		return fmt.Errorf("attribute %s: unsupported source %q", r.Attribute, r.From)
+	}
+
+	for i, m := range r.Methods {
+		r.Methods[i] = strings.ToUpper(m)
+	}
+
+	return nil
+}
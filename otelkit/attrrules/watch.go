@@ -0,0 +1,113 @@
+package attrrules
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Watcher reloads a rule set file into an Engine when the file changes
+// or the process receives SIGHUP
+type Watcher struct {
+	path     string
+	engine   *Engine
+	interval time.Duration
+
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher creates a watcher that polls path every interval
+func NewWatcher(path string, engine *Engine, interval time.Duration) (*Watcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("reload interval must be positive, got %s", interval)
+	}
+
+	w := &Watcher{
+		path:     path,
+		engine:   engine,
+		interval: interval,
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		w.modTime = info.ModTime()
+		w.size = info.Size()
+	}
+
+	return w, nil
+}
+
+// Run watches for file changes and SIGHUP until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Printf("Received SIGHUP, reloading attribute rules from %s", w.path)
+			if err := w.Reload(); err != nil {
+				log.Printf("Error reloading attribute rules: %v", err)
+			}
+		case <-ticker.C:
+			if !w.changed() {
+				continue
+			}
+			if err := w.Reload(); err != nil {
+				log.Printf("Error reloading attribute rules: %v", err)
+			}
+		}
+	}
+}
+
+// Reload loads and validates the rule set file and swaps it into the engine.
+// The current rule set is kept when the file is invalid.
+func (w *Watcher) Reload() error {
+	rules, err := Load(w.path)
+	if err != nil {
+		return fmt.Errorf("keeping rule set %s: %w", w.engine.RuleSet().Version, err)
+	}
+
+	previous := w.engine.RuleSet()
+	if previous.digest == rules.digest {
+		return nil
+	}
+
+	w.engine.Swap(rules)
+	if previous.Version == rules.Version {
+		log.Printf("Attribute rules reloaded: file changed but version %s was not bumped (%d rules)",
+			rules.Version, len(rules.Rules))
+		return nil
+	}
+	log.Printf("Attribute rules reloaded: version %s -> %s (%d rules)",
+		previous.Version, rules.Version, len(rules.Rules))
+
+	return nil
+}
+
+// changed reports whether the file's modification time or size differ
+// from the last observation
+func (w *Watcher) changed() bool {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false
+	}
+
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false
+	}
+
+	w.modTime = info.ModTime()
+	w.size = info.Size()
+	return true
+}
@@ -0,0 +1,67 @@
+package attrrules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewWatcherRejectsNonPositiveInterval(t *testing.T) {
+	engine := NewEngine(&RuleSet{})
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := NewWatcher("rules.yaml", engine, interval); err == nil {
+			t.Errorf("NewWatcher(%s) succeeded, want an error", interval)
+		}
+	}
+}
+
+func TestReloadSwapsOnContentChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("version: \"1\"\nrules:\n  - attribute: apm.http.method\n    from: method\n")
+	rules, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewEngine(rules)
+
+	w, err := NewWatcher(path, engine, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged file keeps the current rule set
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if engine.RuleSet() != rules {
+		t.Error("Reload swapped an unchanged rule set")
+	}
+
+	// Edited rules without a version bump are still applied
+	write("version: \"1\"\nrules:\n  - attribute: apm.http.method\n    from: method\n  - attribute: apm.http.route\n    from: route\n")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(engine.RuleSet().Rules); got != 2 {
+		t.Errorf("rule count after reload = %d, want 2", got)
+	}
+
+	// Invalid files keep the current rule set
+	current := engine.RuleSet()
+	write("target: sideways\n")
+	if err := w.Reload(); err == nil {
+		t.Error("Reload of an invalid file succeeded")
+	}
+	if engine.RuleSet() != current {
+		t.Error("Reload replaced the rule set with an invalid one")
+	}
+}
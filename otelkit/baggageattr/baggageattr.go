@@ -0,0 +1,109 @@
+// Package baggageattr promotes W3C Baggage members set by upstream services,
+// such as the tenant sent by the gateway, to span attributes. Only
+// allow-listed members are promoted, each to a catalogued attribute key, so
+// callers cannot create arbitrary attributes.
+package baggageattr
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// DefaultAllowlist promotes the members set by the upstream gateway
+const DefaultAllowlist = "tenant.id=apm.tenant.id,customer.tier=apm.customer.tier"
+
+// Allowlist maps baggage member names to the attribute key they are
+// promoted to
+type Allowlist map[string]attribute.Key
+
+// ParseAllowlist parses a comma separated list of member=key entries, e.g.
+// "tenant.id=apm.tenant.id". Keys must be catalogued string attributes.
+func ParseAllowlist(spec string) (Allowlist, error) {
+	allow := Allowlist{}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		member, key, ok := strings.Cut(entry, "=")
+		member, key = strings.TrimSpace(member), strings.TrimSpace(key)
+		if !ok || member == "" || key == "" {
+			return nil, fmt.Errorf("invalid baggage attribute %q, want member=key", entry)
+		}
+
+		def, ok := apmattr.Lookup(key)
+		if !ok {
+			return nil, fmt.Errorf("baggage attribute %q: key %s is not declared in the apmattr catalogue", entry, key)
+		}
+		if def.Type != attribute.STRING {
+			return nil, fmt.Errorf("baggage attribute %q: key %s is a %s attribute, baggage values are strings", entry, key, def.Type)
+		}
+
+		allow[member] = def.Key
+	}
+
+	return allow, nil
+}
+
+// Attributes returns the allow-listed members of the baggage in ctx as
+// attributes, ordered by member name
+func (a Allowlist) Attributes(ctx context.Context) []attribute.KeyValue {
+	if len(a) == 0 {
+		return nil
+	}
+	bag := baggage.FromContext(ctx)
+	if bag.Len() == 0 {
+		return nil
+	}
+
+	members := make([]string, 0, len(a))
+	for member := range a {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+
+	var attrs []attribute.KeyValue
+	for _, member := range members {
+		if value := bag.Member(member).Value(); value != "" {
+			attrs = append(attrs, a[member].String(value))
+		}
+	}
+	return attrs
+}
+
+// processor promotes baggage to the attributes of every started span
+type processor struct {
+	allow Allowlist
+}
+
+// NewProcessor returns a span processor that sets the allow-listed members
+// of the parent context's baggage on every span when it starts
+func NewProcessor(allow Allowlist) sdktrace.SpanProcessor {
+	return processor{allow: allow}
+}
+
+// OnStart sets the promoted attributes on the span
+func (p processor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	if attrs := p.allow.Attributes(ctx); len(attrs) > 0 {
+		s.SetAttributes(attrs...)
+	}
+}
+
+// OnEnd does nothing
+func (processor) OnEnd(sdktrace.ReadOnlySpan) {}
+
+// Shutdown does nothing
+func (processor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush does nothing
+func (processor) ForceFlush(context.Context) error { return nil }
@@ -0,0 +1,95 @@
+package baggageattr
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// withBaggage returns a context carrying the baggage header value
+func withBaggage(t *testing.T, header string) context.Context {
+	t.Helper()
+
+	bag, err := baggage.Parse(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return baggage.ContextWithBaggage(context.Background(), bag)
+}
+
+func TestParseAllowlist(t *testing.T) {
+	got, err := ParseAllowlist(DefaultAllowlist + ", tier = apm.user.tier,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Allowlist{
+		"tenant.id":     "apm.tenant.id",
+		"customer.tier": "apm.customer.tier",
+		"tier":          "apm.user.tier",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseAllowlist = %v, want %v", got, want)
+	}
+}
+
+func TestParseAllowlistErrors(t *testing.T) {
+	for _, spec := range []string{"tenant.id", "=apm.tenant.id", "tenant.id=apm.tenant", "age=apm.user.age"} {
+		if _, err := ParseAllowlist(spec); err == nil {
+			t.Errorf("ParseAllowlist(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	allow, err := ParseAllowlist(DefaultAllowlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := withBaggage(t, "tenant.id=acme,session=s3cr3t,customer.tier=gold")
+	want := []attribute.KeyValue{
+		attribute.String("apm.customer.tier", "gold"),
+		attribute.String("apm.tenant.id", "acme"),
+	}
+	if got := allow.Attributes(ctx); !reflect.DeepEqual(got, want) {
+		t.Errorf("Attributes = %v, want %v", got, want)
+	}
+
+	if got := allow.Attributes(context.Background()); got != nil {
+		t.Errorf("Attributes without baggage = %v, want none", got)
+	}
+	if got := allow.Attributes(withBaggage(t, "session=s3cr3t")); got != nil {
+		t.Errorf("Attributes without allow-listed members = %v, want none", got)
+	}
+}
+
+func TestProcessorPromotesToChildSpans(t *testing.T) {
+	allow, err := ParseAllowlist(DefaultAllowlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(NewProcessor(allow)),
+		sdktrace.WithSpanProcessor(recorder),
+	)
+	tracer := tp.Tracer("test")
+
+	ctx, handler := tracer.Start(withBaggage(t, "tenant.id=acme"), "CreateUser")
+	_, db := tracer.Start(ctx, "db:CreateUser")
+	db.End()
+	handler.End()
+
+	for _, s := range recorder.Ended() {
+		if got := s.Attributes(); !reflect.DeepEqual(got, []attribute.KeyValue{attribute.String("apm.tenant.id", "acme")}) {
+			t.Errorf("span %s attributes = %v, want apm.tenant.id", s.Name(), got)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package env reads service configuration from the environment, optionally
+// seeded from a .env file.
+package env
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Load reads KEY=VALUE lines from the file at path and sets the variables
+// that are not already set, so the real environment takes precedence. Empty
+// lines and # comments are skipped. A missing file is not an error: the
+// variables may be set otherwise.
+func Load(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if os.Getenv(key) == "" {
+			os.Setenv(key, strings.TrimSpace(value))
+		}
+	}
+}
+
+// String gets an environment variable or returns a default value when it
+// is unset or blank
+func String(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if strings.TrimSpace(value) == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// Bool gets a boolean environment variable, true when it is "true" in any
+// case, or returns a default value when it is unset or blank
+func Bool(key string, defaultValue bool) bool {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+	return strings.EqualFold(value, "true")
+}
+
+// Int gets an integer environment variable or returns a default value
+// when it is unset or invalid
+func Int(key string, defaultValue int) int {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("WARNING: invalid %s %q, using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// Duration gets a duration environment variable, e.g. 30s, or returns a
+// default value when it is unset or invalid
+func Duration(key string, defaultValue time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("WARNING: invalid %s %q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
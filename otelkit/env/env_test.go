@@ -0,0 +1,64 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "# comment\n\nENV_TEST_NEW = from file \nENV_TEST_SET=from file\nnot a setting\nENV_TEST_URL=http://host/?a=b\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ENV_TEST_NEW", "")
+	t.Setenv("ENV_TEST_SET", "from environment")
+	t.Setenv("ENV_TEST_URL", "")
+
+	Load(path)
+
+	for key, want := range map[string]string{
+		"ENV_TEST_NEW": "from file",
+		"ENV_TEST_SET": "from environment",
+		"ENV_TEST_URL": "http://host/?a=b",
+	} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	Load(filepath.Join(t.TempDir(), "missing.env"))
+}
+
+func TestTypedValues(t *testing.T) {
+	t.Setenv("ENV_TEST_BLANK", "  ")
+	t.Setenv("ENV_TEST_BOOL", "TRUE")
+	t.Setenv("ENV_TEST_NO", "yes")
+	t.Setenv("ENV_TEST_INT", " 42 ")
+	t.Setenv("ENV_TEST_BAD_INT", "many")
+	t.Setenv("ENV_TEST_DURATION", "1m30s")
+	t.Setenv("ENV_TEST_BAD_DURATION", "soon")
+
+	if got := String("ENV_TEST_BLANK", "default"); got != "default" {
+		t.Errorf("String(blank) = %q, want the default", got)
+	}
+	if !Bool("ENV_TEST_BOOL", false) || Bool("ENV_TEST_NO", true) || !Bool("ENV_TEST_BLANK", true) {
+		t.Error("Bool did not parse true, other values and blank values as expected")
+	}
+	if got := Int("ENV_TEST_INT", 1); got != 42 {
+		t.Errorf("Int = %d, want 42", got)
+	}
+	if got := Int("ENV_TEST_BAD_INT", 1); got != 1 {
+		t.Errorf("Int(invalid) = %d, want the default", got)
+	}
+	if got := Duration("ENV_TEST_DURATION", time.Second); got != 90*time.Second {
+		t.Errorf("Duration = %s, want 1m30s", got)
+	}
+	if got := Duration("ENV_TEST_BAD_DURATION", time.Second); got != time.Second {
+		t.Errorf("Duration(invalid) = %s, want the default", got)
+	}
+}
@@ -0,0 +1,313 @@
+// Package httpclient provides an instrumented outbound HTTP client. Every
+// request sent on the wire, including retries and redirects, gets a client
+// span with the HTTP semantic convention attributes, and the trace context
+// is injected into its headers, so downstream services continue the trace
+// instead of starting a disconnected one.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Resend reasons recorded in apm.http.client.resend_reason
+const (
+	reasonRetry    = "retry"
+	reasonRedirect = "redirect"
+)
+
+// Config configures the client
+type Config struct {
+	// TracerName names the tracer of the client spans
+	TracerName string
+	// Propagator injects the trace context into requests; nil uses the
+	// global propagator, see telemetry.NewPropagator
+	Propagator propagation.TextMapPropagator
+	// Timeout bounds a call including its retries and redirects
+	Timeout time.Duration
+	// MaxRetries is how many times an idempotent request is sent again
+	// after a connection error or a 502, 503 or 504 response
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled for each
+	// further retry
+	RetryBackoff time.Duration
+	// Base sends the requests; nil uses http.DefaultTransport
+	Base http.RoundTripper
+}
+
+// New creates an HTTP client using an instrumented transport
+func New(cfg Config) *http.Client {
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: NewTransport(cfg),
+	}
+}
+
+// Transport is an http.RoundTripper that traces, propagates and retries
+// requests. Redirects are followed by the http.Client, which sends each hop
+// through the transport again.
+type Transport struct {
+	cfg    Config
+	tracer trace.Tracer
+}
+
+// NewTransport creates an instrumented transport
+func NewTransport(cfg Config) *Transport {
+	if cfg.Base == nil {
+		cfg.Base = http.DefaultTransport
+	}
+	return &Transport{cfg: cfg, tracer: otel.Tracer(cfg.TracerName)}
+}
+
+// RoundTrip sends the request, retrying it as configured
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The http.Client links a redirected request to the response that
+	// caused it
+	redirects := 0
+	for r := req; r.Response != nil && r.Response.Request != nil; r = r.Response.Request {
+		redirects++
+	}
+
+	backoff := t.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		reason := ""
+		if attempt > 0 {
+			reason = reasonRetry
+		} else if redirects > 0 {
+			reason = reasonRedirect
+		}
+
+		resp, err := t.send(req, redirects+attempt, reason)
+		if attempt >= t.cfg.MaxRetries || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), backoff); err != nil {
+			return nil, err
+		}
+		backoff *= 2
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// send sends a single request within a client span. The span ends when the
+// response body is read to the end or closed.
+func (t *Transport) send(req *http.Request, resendCount int, reason string) (*http.Response, error) {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.URLFull(redactedURL(req)),
+		semconv.ServerAddress(req.URL.Hostname()),
+	}
+	if port := serverPort(req); port > 0 {
+		attrs = append(attrs, semconv.ServerPort(port))
+	}
+	if req.ContentLength > 0 {
+		attrs = append(attrs, semconv.HTTPRequestBodySize(int(req.ContentLength)))
+	}
+	if ua := req.UserAgent(); ua != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(ua))
+	}
+	if resendCount > 0 {
+		attrs = append(attrs,
+			semconv.HTTPRequestResendCount(resendCount),
+			apmattr.HTTPClientResendReason.String(reason),
+		)
+	}
+
+	ctx, span := t.tracer.Start(req.Context(), req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	out := req.Clone(ctx)
+	t.propagator().Inject(ctx, propagation.HeaderCarrier(out.Header))
+
+	resp, err := t.cfg.Base.RoundTrip(out)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(semconv.ErrorTypeKey.String(errorType(err)))
+		span.End()
+		return nil, err
+	}
+
+	span.SetAttributes(
+		semconv.HTTPResponseStatusCode(resp.StatusCode),
+		semconv.NetworkProtocolVersion(strconv.Itoa(resp.ProtoMajor)+"."+strconv.Itoa(resp.ProtoMinor)),
+	)
+	// Client spans fail on 4xx and 5xx responses
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+		span.SetAttributes(semconv.ErrorTypeKey.String(strconv.Itoa(resp.StatusCode)))
+	}
+
+	if resp.Body == nil || resp.Body == http.NoBody {
+		span.SetAttributes(semconv.HTTPResponseBodySize(0))
+		span.End()
+		return resp, nil
+	}
+	resp.Body = &tracedBody{ReadCloser: resp.Body, span: span}
+	return resp, nil
+}
+
+// propagator returns the configured or the global propagator
+func (t *Transport) propagator() propagation.TextMapPropagator {
+	if t.cfg.Propagator != nil {
+		return t.cfg.Propagator
+	}
+	return otel.GetTextMapPropagator()
+}
+
+// tracedBody ends the client span once the response body is consumed,
+// recording its size
+type tracedBody struct {
+	io.ReadCloser
+	span trace.Span
+
+	mu   sync.Mutex
+	size int
+	done bool
+}
+
+// Read reads from the body and ends the span at EOF or on an error
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.mu.Lock()
+	b.size += n
+	b.mu.Unlock()
+
+	switch {
+	case err == io.EOF:
+		b.end(nil)
+	case err != nil:
+		b.end(err)
+	}
+	return n, err
+}
+
+// Close closes the body and ends the span
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.end(nil)
+	return err
+}
+
+// end records the body size and a read error, then ends the span once
+func (b *tracedBody) end(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.done {
+		return
+	}
+	b.done = true
+
+	b.span.SetAttributes(semconv.HTTPResponseBodySize(b.size))
+	if err != nil {
+		b.span.RecordError(err)
+		b.span.SetStatus(codes.Error, err.Error())
+	}
+	b.span.End()
+}
+
+// retryable reports whether the request may be sent again: the method is
+// idempotent, the body can be rewound and the failure is transient
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		// A cancelled or expired call is not retried
+		return req.Context().Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// errorType classifies a transport error for the error.type attribute
+func errorType(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return semconv.ErrorTypeOther.Value.AsString()
+	}
+}
+
+// redactedURL returns the request URL without user credentials
+func redactedURL(req *http.Request) string {
+	u := *req.URL
+	u.User = nil
+	return u.String()
+}
+
+// serverPort returns the port the request is sent to
+func serverPort(req *http.Request) int {
+	if port, err := strconv.Atoi(req.URL.Port()); err == nil {
+		return port
+	}
+	switch req.URL.Scheme {
+	case "https":
+		return 443
+	case "http":
+		return 80
+	}
+	return 0
+}
@@ -0,0 +1,225 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"otelkit/spantest"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newClient returns a client propagating W3C TraceContext and B3
+func newClient(maxRetries int) *http.Client {
+	return New(Config{
+		TracerName: "test",
+		Propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, b3.New()),
+		MaxRetries: maxRetries,
+	})
+}
+
+// get sends a GET request within a parent span and reads the whole body
+func get(t *testing.T, client *http.Client, url string) *http.Response {
+	t.Helper()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "call_external_api")
+	defer parent.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+	return resp
+}
+
+// clientSpans returns the recorded client spans in start order
+func clientSpans(rec *spantest.Recorder) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, s := range rec.Spans() {
+		if s.SpanKind() == trace.SpanKindClient {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func TestPropagatesTraceContext(t *testing.T) {
+	rec := spantest.New(t)
+
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	get(t, newClient(0), srv.URL+"/posts/1?full=true")
+
+	span := rec.Span("GET").
+		ChildOf("call_external_api").
+		HasAttribute("http.request.method", "GET").
+		HasAttribute("url.full", srv.URL+"/posts/1?full=true").
+		HasAttribute("server.address", "127.0.0.1").
+		HasAttribute("http.response.status_code", http.StatusOK).
+		HasAttribute("http.response.body.size", 5).
+		HasAttribute("network.protocol.version", "1.1").
+		NoAttribute("http.request.resend_count").
+		HasStatus(codes.Unset)
+
+	sc := span.ReadOnly().SpanContext()
+	if got := header.Get("traceparent"); !strings.Contains(got, sc.TraceID().String()+"-"+sc.SpanID().String()) {
+		t.Errorf("traceparent = %q, want the client span context", got)
+	}
+	if got := header.Get("b3"); !strings.HasPrefix(got, sc.TraceID().String()+"-"+sc.SpanID().String()) {
+		t.Errorf("b3 = %q, want the client span context", got)
+	}
+}
+
+func TestSpanEndsWhenBodyIsConsumed(t *testing.T) {
+	rec := spantest.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	resp, err := newClient(0).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.NoSpan("GET")
+
+	resp.Body.Close()
+	rec.Span("GET").HasAttributeKey("http.response.body.size")
+}
+
+func TestRetriesTransientFailures(t *testing.T) {
+	rec := spantest.New(t)
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	if resp := get(t, newClient(2), srv.URL); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200 after retries", resp.StatusCode)
+	}
+
+	spans := clientSpans(rec)
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d client spans, want 3", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("first attempt status = %v, want Error", spans[0].Status().Code)
+	}
+	for i, s := range spans[1:] {
+		got := map[string]string{}
+		for _, kv := range s.Attributes() {
+			got[string(kv.Key)] = kv.Value.Emit()
+		}
+		if got["http.request.resend_count"] != []string{"1", "2"}[i] || got["apm.http.client.resend_reason"] != "retry" {
+			t.Errorf("retry %d attributes = %v", i+1, got)
+		}
+	}
+}
+
+func TestDoesNotRetryPost(t *testing.T) {
+	rec := spantest.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	resp, err := newClient(2).Post(srv.URL, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if spans := clientSpans(rec); len(spans) != 1 {
+		t.Errorf("recorded %d client spans, want 1", len(spans))
+	}
+	rec.Span("POST").
+		HasAttribute("http.request.body.size", 2).
+		HasAttribute("error.type", "503").
+		HasStatus(codes.Error)
+}
+
+func TestRecordsRedirects(t *testing.T) {
+	rec := spantest.New(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("moved"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	get(t, newClient(0), srv.URL+"/old")
+
+	spans := clientSpans(rec)
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d client spans, want 2", len(spans))
+	}
+
+	got := map[string]string{}
+	for _, kv := range spans[1].Attributes() {
+		got[string(kv.Key)] = kv.Value.Emit()
+	}
+	if got["url.full"] != srv.URL+"/new" || got["http.request.resend_count"] != "1" || got["apm.http.client.resend_reason"] != "redirect" {
+		t.Errorf("redirect span attributes = %v", got)
+	}
+}
+
+func TestRecordsTransportErrors(t *testing.T) {
+	rec := spantest.New(t)
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	if _, err := newClient(0).Get(url); err == nil {
+		t.Fatal("request to a closed server succeeded")
+	}
+
+	rec.Span("GET").
+		HasAttribute("error.type", "_OTHER").
+		HasEvent("exception").
+		HasStatus(codes.Error)
+}
+
+func TestURLWithoutCredentials(t *testing.T) {
+	rec := spantest.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	get(t, newClient(0), strings.Replace(srv.URL, "http://", "http://user:secret@", 1))
+
+	rec.Span("GET").HasAttribute("url.full", srv.URL)
+}
@@ -0,0 +1,79 @@
+package instrument
+
+import (
+	"errors"
+	"net/http"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Error classes
+const (
+	// ErrorClassClient is a caller error (HTTP 4xx)
+	ErrorClassClient = "client"
+	// ErrorClassServer is a server fault (HTTP 5xx)
+	ErrorClassServer = "server"
+)
+
+// recordedError marks an error whose exception event is already on a span
+type recordedError struct {
+	error
+}
+
+// Unwrap returns the recorded error
+func (e *recordedError) Unwrap() error {
+	return e.error
+}
+
+// Recorded reports whether err, or an error it wraps, was returned by RecordError
+func Recorded(err error) bool {
+	var recorded *recordedError
+	return errors.As(err, &recorded)
+}
+
+// RecordError records err on span with the apm.error.* attributes, classified
+// by the HTTP status code it maps to, and returns err marked as recorded.
+//
+// Following the OTel HTTP semantic conventions, client errors (4xx) are not
+// failures of the operation: the exception event is recorded but the span
+// status stays unset. Server errors (5xx) record the exception with a stack
+// trace and set the span status to Error.
+//
+// The exception event is only added by the span that owns the error: when
+// err was already recorded further down the call chain, callers still get
+// the attributes and status but no duplicate event.
+func RecordError(span trace.Span, err error, errorType string, statusCode int) error {
+	class := ErrorClassServer
+	if statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError {
+		class = ErrorClassClient
+	}
+
+	span.SetAttributes(
+		apmattr.Error.Bool(true),
+		apmattr.ErrorType.String(errorType),
+		apmattr.ErrorMessage.String(err.Error()),
+		apmattr.ErrorClass.String(class),
+		apmattr.ErrorStatusCode.Int(statusCode),
+		semconv.ErrorTypeKey.String(errorType),
+	)
+
+	if class == ErrorClassServer {
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	if Recorded(err) {
+		return err
+	}
+
+	if class == ErrorClassClient {
+		span.RecordError(err)
+	} else {
+		span.RecordError(err, trace.WithStackTrace(true))
+	}
+
+	return &recordedError{err}
+}
@@ -0,0 +1,99 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// exceptionEvents counts the exception events on a span
+func exceptionEvents(s sdktrace.ReadOnlySpan) int {
+	n := 0
+	for _, e := range s.Events() {
+		if e.Name == "exception" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRecordErrorOncePerSpan(t *testing.T) {
+	for _, mode := range []Mode{ModeEnrich, ModeChildSpan, ModeBoth} {
+		t.Run(string(mode), func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			instr := &Instrumenter{mode: mode, tracer: tp.Tracer("test")}
+
+			ctx, server := tp.Tracer("test").Start(context.Background(), "server")
+
+			// Handler span wrapping a repository span, as in the users package
+			ctx, handler := instr.Start(ctx, "handler")
+			_, repo := instr.Start(ctx, "repo")
+			err := RecordError(repo, errors.New("connection refused"), "unavailable", http.StatusServiceUnavailable)
+			repo.End()
+
+			RecordError(handler, fmt.Errorf("error getting user: %w", err), "unavailable", http.StatusServiceUnavailable)
+			handler.End()
+			server.End()
+
+			total := 0
+			for _, s := range recorder.Ended() {
+				if n := exceptionEvents(s); n > 1 {
+					t.Errorf("span %s has %d exception events", s.Name(), n)
+				}
+				total += exceptionEvents(s)
+				if s.Name() != "server" || mode != ModeChildSpan {
+					if s.Status().Code != codes.Error {
+						t.Errorf("span %s status = %v, want Error", s.Name(), s.Status().Code)
+					}
+				}
+			}
+			if total != 1 {
+				t.Errorf("recorded %d exception events, want 1", total)
+			}
+		})
+	}
+}
+
+func TestRecordErrorClientErrorKeepsStatus(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	_, span := tp.Tracer("test").Start(context.Background(), "op")
+	err := RecordError(span, errors.New("user not found"), "not_found", http.StatusNotFound)
+	span.End()
+
+	if !Recorded(err) {
+		t.Error("returned error is not marked as recorded")
+	}
+
+	s := recorder.Ended()[0]
+	if s.Status().Code != codes.Unset {
+		t.Errorf("status = %v, want Unset for a client error", s.Status().Code)
+	}
+	if exceptionEvents(s) != 1 {
+		t.Errorf("exception events = %d, want 1", exceptionEvents(s))
+	}
+	if got := attrValue(s, apmattr.ErrorClass.Key()); got != ErrorClassClient {
+		t.Errorf("%s = %q, want %q", apmattr.ErrorClass.Key(), got, ErrorClassClient)
+	}
+}
+
+// attrValue returns the emitted value of the span attribute key
+func attrValue(s sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
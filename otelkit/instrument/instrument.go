@@ -0,0 +1,126 @@
+// Package instrument implements the two custom attribute strategies from
+// Methods.md behind a single API, so the same handler and repository code can
+// run with active span enrichment, explicit child spans, or both.
+package instrument
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"otelkit/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Mode selects the instrumentation strategy
+type Mode string
+
+// Instrumentation modes
+const (
+	// ModeEnrich adds attributes to the active span (Active Span Enrichment)
+	ModeEnrich Mode = "enrich"
+	// ModeChildSpan starts a new child span per operation (Explicit Span Creation)
+	ModeChildSpan Mode = "child-span"
+	// ModeBoth starts a child span and also enriches the active span
+	ModeBoth Mode = "both"
+)
+
+// ParseMode parses an instrumentation mode name
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case ModeEnrich, ModeChildSpan, ModeBoth:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported instrumentation mode %q", s)
+	}
+}
+
+// Instrumenter starts operation spans according to the configured mode
+type Instrumenter struct {
+	mode   Mode
+	tracer trace.Tracer
+}
+
+// New creates an instrumenter using the global tracer named tracerName
+func New(tracerName string, mode Mode) *Instrumenter {
+	return &Instrumenter{
+		mode:   mode,
+		tracer: otel.Tracer(tracerName),
+	}
+}
+
+// Mode returns the instrumentation mode
+func (i *Instrumenter) Mode() Mode {
+	return i.mode
+}
+
+// Start returns the span custom attributes for the operation name should be
+// set on. The caller must always call End on the returned span; it only ends
+// spans the instrumenter started, never the enriched active span.
+//
+// In enrich mode, a non-recording active span (eBPF Auto SDK or no-op
+// tracing) would silently drop the attributes, so a child span is started
+// instead.
+//
+// When spans are exported outside the process, the promoted baggage
+// members are set on the returned span and attributes set on it are
+// redacted and limited first (see telemetry.PromoteBaggage and WrapSpan).
+func (i *Instrumenter) Start(ctx context.Context, name string) (context.Context, trace.Span) {
+	ctx, span := i.start(ctx, name)
+	telemetry.PromoteBaggage(ctx, span)
+	return ctx, telemetry.WrapSpan(span)
+}
+
+// start returns the span for the operation according to the mode
+func (i *Instrumenter) start(ctx context.Context, name string) (context.Context, trace.Span) {
+	active := trace.SpanFromContext(ctx)
+
+	switch i.mode {
+	case ModeChildSpan:
+		return i.tracer.Start(ctx, name)
+	case ModeBoth:
+		ctx, child := i.tracer.Start(ctx, name)
+		if !active.IsRecording() {
+			return ctx, child
+		}
+		return ctx, &fanoutSpan{Span: child, parent: active}
+	default:
+		if active.IsRecording() {
+			return ctx, borrowedSpan{Span: active}
+		}
+		telemetry.NoteEnrichmentFallback()
+		return i.tracer.Start(ctx, name)
+	}
+}
+
+// borrowedSpan is an active span owned by someone else; End is a no-op
+type borrowedSpan struct {
+	trace.Span
+}
+
+// End does nothing, the span's owner ends it
+func (borrowedSpan) End(...trace.SpanEndOption) {}
+
+// fanoutSpan is a child span whose attributes and status are also applied
+// to the enriched parent span. Exception events stay on the child span, the
+// span that owns the operation.
+type fanoutSpan struct {
+	trace.Span
+	parent trace.Span
+}
+
+// SetAttributes sets the attributes on the child and the parent span
+func (s *fanoutSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.Span.SetAttributes(kv...)
+	s.parent.SetAttributes(kv...)
+}
+
+// SetStatus sets the status on the child and the parent span
+func (s *fanoutSpan) SetStatus(code codes.Code, description string) {
+	s.Span.SetStatus(code, description)
+	s.parent.SetStatus(code, description)
+}
@@ -0,0 +1,370 @@
+// Package migrate runs ordered, versioned SQL migrations against PostgreSQL
+// or SQLite.
+//
+// Migrations are read from an fs.FS (usually embedded) as pairs of files named
+// NNNN_description.up.sql and NNNN_description.down.sql. Applied versions are
+// recorded in the schema_migrations table, and on PostgreSQL a session-level
+// advisory lock serialises runners so several replicas can start at once.
+// Every migration step is traced as its own span.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultLockID is the advisory lock key used when none is configured
+const DefaultLockID int64 = 4_815_162_342
+
+// Directions
+const (
+	Up   = "up"
+	Down = "down"
+)
+
+// Dialect holds the database specific statements used by the migrator
+type Dialect struct {
+	// Lock and Unlock take and release the lock serialising runners; they
+	// receive the lock ID and are skipped when empty
+	Lock   string
+	Unlock string
+	// Record inserts an applied version and name
+	Record string
+	// Forget deletes a reverted version
+	Forget string
+}
+
+// Dialects
+var (
+	// Postgres serialises runners with a session-level advisory lock
+	Postgres = Dialect{
+		Lock:   "SELECT pg_advisory_lock($1)",
+		Unlock: "SELECT pg_advisory_unlock($1)",
+		Record: "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+		Forget: "DELETE FROM schema_migrations WHERE version = $1",
+	}
+	// SQLite relies on the database file lock taken by each migration transaction
+	SQLite = Dialect{
+		Record: "INSERT INTO schema_migrations (version, name) VALUES (?, ?)",
+		Forget: "DELETE FROM schema_migrations WHERE version = ?",
+	}
+)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status reports whether a migration has been applied
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Load reads the migrations in dir of fsys, ordered by version
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(name, ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+		direction = strings.TrimPrefix(direction, ".")
+		if direction != Up && direction != Down {
+			return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql suffix", name)
+		}
+
+		prefix, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNNN_description name", name)
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", name, err)
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %w", name, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, label)
+		}
+
+		if direction == Up {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up migration", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	dialect    Dialect
+	lockID     int64
+	tracer     trace.Tracer
+}
+
+// New creates a new PostgreSQL migrator for the given migrations
+func New(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		dialect:    Postgres,
+		lockID:     DefaultLockID,
+		tracer:     otel.Tracer("migrate"),
+	}
+}
+
+// WithDialect switches the migrator to another database dialect
+func (m *Migrator) WithDialect(dialect Dialect) *Migrator {
+	m.dialect = dialect
+	return m
+}
+
+// Up applies all pending migrations and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	ctx, span := m.tracer.Start(ctx, "migrate:up")
+	defer span.End()
+
+	applied := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := m.step(ctx, conn, migration, Up); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+
+	span.SetAttributes(apmattr.MigrationApplied.Int(applied))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return applied, err
+}
+
+// Down reverts the most recently applied migrations, up to steps of them
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	ctx, span := m.tracer.Start(ctx, "migrate:down")
+	defer span.End()
+
+	reverted := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down migration", migration.Version, migration.Name)
+			}
+			if err := m.step(ctx, conn, migration, Down); err != nil {
+				return err
+			}
+			reverted++
+		}
+		return nil
+	})
+
+	span.SetAttributes(apmattr.MigrationReverted.Int(reverted))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return reverted, err
+}
+
+// Status lists every known migration and when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	done, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := Status{Version: migration.Version, Name: migration.Name}
+		if at, ok := done[migration.Version]; ok {
+			s.AppliedAt = &at
+		}
+		statuses = append(statuses, s)
+	}
+
+	return statuses, nil
+}
+
+// withLock runs fn on a dedicated connection holding the advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	if m.dialect.Lock != "" {
+		if _, err := conn.ExecContext(ctx, m.dialect.Lock, m.lockID); err != nil {
+			return fmt.Errorf("error acquiring migration lock: %w", err)
+		}
+	}
+	if m.dialect.Unlock != "" {
+		defer func() {
+			if _, err := conn.ExecContext(context.Background(), m.dialect.Unlock, m.lockID); err != nil {
+				log.Printf("Error releasing migration lock: %v", err)
+			}
+		}()
+	}
+
+	return fn(conn)
+}
+
+// applied returns the applied migration versions and their timestamps
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("error scanning schema_migrations: %w", err)
+		}
+		done[version] = at
+	}
+
+	return done, rows.Err()
+}
+
+// step applies or reverts a single migration in its own transaction and span
+func (m *Migrator) step(ctx context.Context, conn *sql.Conn, migration Migration, direction string) (err error) {
+	ctx, span := m.tracer.Start(ctx, fmt.Sprintf("migrate:%s %d_%s", direction, migration.Version, migration.Name))
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.MigrationVersion.Int64(migration.Version),
+		apmattr.MigrationName.String(migration.Name),
+		apmattr.MigrationDirection.String(direction),
+	)
+
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting migration %d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	script := migration.Up
+	record := m.dialect.Record
+	args := []interface{}{migration.Version, migration.Name}
+	if direction == Down {
+		script = migration.Down
+		record = m.dialect.Forget
+		args = args[:1]
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("error running migration %d_%s %s: %w", migration.Version, migration.Name, direction, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("error recording migration %d: %w", migration.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing migration %d: %w", migration.Version, err)
+	}
+
+	log.Printf("Migration %d_%s %s applied", migration.Version, migration.Name, direction)
+	return nil
+}
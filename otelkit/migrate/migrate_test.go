@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_index.up.sql":   {Data: []byte("CREATE INDEX i ON t (c);")},
+		"migrations/0002_add_index.down.sql": {Data: []byte("DROP INDEX i;")},
+		"migrations/0001_create.up.sql":      {Data: []byte("CREATE TABLE t (c INT);")},
+		"migrations/0010_seed.up.sql":        {Data: []byte("INSERT INTO t VALUES (1);")},
+		"migrations/README.md":               {Data: []byte("ignored")},
+	}
+
+	migrations, err := Load(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Migration{
+		{Version: 1, Name: "create", Up: "CREATE TABLE t (c INT);"},
+		{Version: 2, Name: "add_index", Up: "CREATE INDEX i ON t (c);", Down: "DROP INDEX i;"},
+		{Version: 10, Name: "seed", Up: "INSERT INTO t VALUES (1);"},
+	}
+	if len(migrations) != len(want) {
+		t.Fatalf("loaded %d migrations, want %d", len(migrations), len(want))
+	}
+	for i := range want {
+		if migrations[i] != want[i] {
+			t.Errorf("migration %d = %+v, want %+v", i, migrations[i], want[i])
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"bad direction", fstest.MapFS{"m/0001_a.sideways.sql": {}}},
+		{"missing description", fstest.MapFS{"m/0001.up.sql": {}}},
+		{"bad version", fstest.MapFS{"m/one_a.up.sql": {}}},
+		{"conflicting names", fstest.MapFS{
+			"m/0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"m/0001_b.down.sql": {Data: []byte("SELECT 1;")},
+		}},
+		{"down without up", fstest.MapFS{"m/0001_a.down.sql": {Data: []byte("SELECT 1;")}}},
+		{"missing directory", fstest.MapFS{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.files, "m"); err == nil {
+				t.Error("Load succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestRunCommandUsage(t *testing.T) {
+	m := New(nil, nil)
+
+	for _, args := range [][]string{nil, {"sideways"}, {"down", "0"}, {"down", "two"}} {
+		err := RunCommand(context.Background(), m, args, io.Discard)
+		if err == nil {
+			t.Errorf("RunCommand(%q) succeeded, want an error", args)
+			continue
+		}
+		if len(args) != 1 && len(args) != 0 {
+			continue
+		}
+		if !strings.Contains(err.Error(), Usage) {
+			t.Errorf("RunCommand(%q) error %q does not include the usage", args, err)
+		}
+	}
+}
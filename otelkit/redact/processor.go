@@ -0,0 +1,221 @@
+package redact
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// processor redacts ended spans before handing them to the next processor
+type processor struct {
+	next     sdktrace.SpanProcessor
+	redactor *Redactor
+}
+
+// NewProcessor returns a span processor that redacts span and event
+// attributes of ended spans, then passes them to next, usually the batch
+// or simple processor of the exporter
+func NewProcessor(next sdktrace.SpanProcessor, redactor *Redactor) sdktrace.SpanProcessor {
+	return &processor{next: next, redactor: redactor}
+}
+
+// OnStart passes the started span to the next processor
+func (p *processor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(ctx, s)
+}
+
+// OnEnd redacts the span and passes it to the next processor
+func (p *processor) OnEnd(s sdktrace.ReadOnlySpan) {
+	attrs, redacted := p.redactor.Redact(s.Attributes())
+
+	events := s.Events()
+	var redactedEvents []sdktrace.Event
+	for i, e := range events {
+		eventAttrs, keys := p.redactor.Redact(e.Attributes)
+		if keys == nil {
+			continue
+		}
+		if redactedEvents == nil {
+			redactedEvents = append([]sdktrace.Event(nil), events...)
+		}
+		redactedEvents[i].Attributes = eventAttrs
+		redacted = append(redacted, keys...)
+	}
+
+	if len(redacted) == 0 {
+		p.next.OnEnd(s)
+		return
+	}
+	if redactedEvents == nil {
+		redactedEvents = events
+	}
+
+	attrs = markRedacted(attrs, redacted)
+	p.next.OnEnd(&redactedSpan{ReadOnlySpan: s, attrs: attrs, events: redactedEvents})
+}
+
+// Shutdown shuts the next processor down
+func (p *processor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush flushes the next processor
+func (p *processor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// redactedSpan is an ended span with redacted attributes and events
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+	attrs  []attribute.KeyValue
+	events []sdktrace.Event
+}
+
+// Attributes returns the redacted span attributes
+func (s *redactedSpan) Attributes() []attribute.KeyValue {
+	return s.attrs
+}
+
+// Events returns the events with redacted attributes
+func (s *redactedSpan) Events() []sdktrace.Event {
+	return s.events
+}
+
+// markRedacted sets apm.redacted.keys to the union of redacted and the keys
+// already listed in attrs
+func markRedacted(attrs []attribute.KeyValue, redacted []string) []attribute.KeyValue {
+	key := apmattr.RedactedKeys.Key()
+	seen := map[string]bool{}
+	out := make([]attribute.KeyValue, 0, len(attrs)+1)
+
+	for _, kv := range attrs {
+		if kv.Key == key {
+			for _, k := range kv.Value.AsStringSlice() {
+				seen[k] = true
+			}
+			continue
+		}
+		out = append(out, kv)
+	}
+	for _, k := range redacted {
+		seen[k] = true
+	}
+
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return append(out, apmattr.RedactedKeys.StringSlice(keys))
+}
+
+// Wrap returns span with a SetAttributes that redacts attributes before
+// they are set. It is the redaction hook for spans that are not exported
+// through an SDK processor, such as those of the eBPF Auto SDK. The span
+// may be wrapped more than once, e.g. by each instrumentation enriching a
+// borrowed active span; apm.redacted.keys lists the keys redacted by all
+// of them.
+func Wrap(span trace.Span, redactor *Redactor) trace.Span {
+	if redactor == nil {
+		return span
+	}
+	return &redactingSpan{Span: span, redactor: redactor}
+}
+
+// redactingSpan redacts attributes before setting them on the wrapped span
+type redactingSpan struct {
+	trace.Span
+	redactor *Redactor
+
+	mu sync.Mutex
+	// redacted are the keys redacted on a span without a valid span
+	// context, which cannot be tracked across wrappers
+	redacted []string
+}
+
+// SetAttributes redacts kv, sets it and updates apm.redacted.keys
+func (s *redactingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	attrs, redacted := s.redactor.Redact(kv)
+	if len(redacted) == 0 {
+		s.Span.SetAttributes(attrs...)
+		return
+	}
+	s.Span.SetAttributes(append(attrs, s.markRedacted(redacted))...)
+}
+
+// End forgets the keys redacted on the span, then ends it
+func (s *redactingSpan) End(options ...trace.SpanEndOption) {
+	if sc := s.Span.SpanContext(); sc.IsValid() {
+		wrapped.forget(spanKey{sc.TraceID(), sc.SpanID()})
+	}
+	s.Span.End(options...)
+}
+
+// markRedacted returns apm.redacted.keys listing redacted and the keys
+// redacted on the span before, through this or another wrapper
+func (s *redactingSpan) markRedacted(redacted []string) attribute.KeyValue {
+	if sc := s.Span.SpanContext(); sc.IsValid() {
+		return wrapped.add(spanKey{sc.TraceID(), sc.SpanID()}, redacted)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kv := markRedacted([]attribute.KeyValue{apmattr.RedactedKeys.StringSlice(s.redacted)}, redacted)[0]
+	s.redacted = kv.Value.AsStringSlice()
+	return kv
+}
+
+// maxTrackedSpans bounds the spans whose redacted keys are tracked; the
+// spans tracked longest ago are forgotten first. Borrowed spans are ended
+// by their owner rather than through a wrapper, so End alone cannot bound
+// the tracker.
+const maxTrackedSpans = 4096
+
+// spanKey identifies a span across its wrappers
+type spanKey struct {
+	traceID trace.TraceID
+	spanID  trace.SpanID
+}
+
+// redactedKeys tracks the keys redacted per span
+type redactedKeys struct {
+	mu    sync.Mutex
+	keys  map[spanKey][]string
+	order [maxTrackedSpans]spanKey
+	next  int
+}
+
+// wrapped tracks the keys redacted on the spans returned by Wrap
+var wrapped = &redactedKeys{keys: map[spanKey][]string{}}
+
+// add adds redacted to the keys of the span and returns their union as
+// apm.redacted.keys
+func (r *redactedKeys) add(span spanKey, redacted []string) attribute.KeyValue {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys, ok := r.keys[span]
+	if !ok {
+		delete(r.keys, r.order[r.next])
+		r.order[r.next] = span
+		r.next = (r.next + 1) % maxTrackedSpans
+	}
+
+	kv := markRedacted([]attribute.KeyValue{apmattr.RedactedKeys.StringSlice(keys)}, redacted)[0]
+	r.keys[span] = kv.Value.AsStringSlice()
+	return kv
+}
+
+// forget stops tracking the span
+func (r *redactedKeys) forget(span spanKey) {
+	r.mu.Lock()
+	delete(r.keys, span)
+	r.mu.Unlock()
+}
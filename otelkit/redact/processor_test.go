@@ -0,0 +1,190 @@
+package redact_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"otelkit/instrument"
+	"otelkit/redact"
+	"otelkit/users"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const email = "john@example.com"
+
+// newProvider returns a provider exporting through the redaction processor
+func newProvider(r *redact.Redactor) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(redact.NewProcessor(sdktrace.NewSimpleSpanProcessor(exporter), r)),
+	)
+	return tp, exporter
+}
+
+// assertNoEmail fails when an exported attribute or event attribute contains the email
+func assertNoEmail(t *testing.T, spans tracetest.SpanStubs) {
+	t.Helper()
+
+	check := func(span string, attrs []attribute.KeyValue) {
+		for _, kv := range attrs {
+			if strings.Contains(kv.Value.Emit(), email) {
+				t.Errorf("span %s exported %s = %s", span, kv.Key, kv.Value.Emit())
+			}
+		}
+	}
+	for _, s := range spans {
+		check(s.Name, s.Attributes)
+		for _, e := range s.Events {
+			check(s.Name, e.Attributes)
+		}
+	}
+}
+
+// attr returns the value of key on the span
+func attr(s tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestProcessorRedactsBeforeExport(t *testing.T) {
+	tp, exporter := newProvider(redact.New(redact.DefaultPolicies(), "salt"))
+
+	_, span := tp.Tracer("test").Start(context.Background(), "CreateUser")
+	span.SetAttributes(
+		attribute.String("apm.user.email", email),
+		attribute.String("apm.http.route", "/users"),
+	)
+	span.AddEvent("validation", trace.WithAttributes(attribute.String("apm.user.email", email)))
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("exported %d spans, want 1", len(spans))
+	}
+	assertNoEmail(t, spans)
+
+	if v, _ := attr(spans[0], "apm.http.route"); v.AsString() != "/users" {
+		t.Errorf("apm.http.route = %q, want it unchanged", v.AsString())
+	}
+	if v, _ := attr(spans[0], "apm.redacted.keys"); strings.Join(v.AsStringSlice(), ",") != "apm.user.email" {
+		t.Errorf("apm.redacted.keys = %v, want [apm.user.email]", v.AsStringSlice())
+	}
+}
+
+func TestProcessorLeavesCleanSpans(t *testing.T) {
+	tp, exporter := newProvider(redact.New(redact.DefaultPolicies(), "salt"))
+
+	_, span := tp.Tracer("test").Start(context.Background(), "GetAllUsers")
+	span.SetAttributes(attribute.String("apm.http.route", "/users"))
+	span.End()
+
+	if _, ok := attr(exporter.GetSpans()[0], "apm.redacted.keys"); ok {
+		t.Error("span without personal data is marked as redacted")
+	}
+}
+
+func TestUserHandlerEmailsNeverExported(t *testing.T) {
+	tp, exporter := newProvider(redact.New(redact.DefaultPolicies(), "salt"))
+
+	// In enrich mode every custom attribute lands on the server span
+	instr := instrument.New("test", instrument.ModeEnrich)
+	router := users.NewTracedUserHandler(users.NewUserHandler(users.NewMemoryStore(instr), instr))
+
+	serve := func(method, target, body string) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		ctx, span := tp.Tracer("test").Start(req.Context(), "server", trace.WithSpanKind(trace.SpanKindServer))
+		router.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+		span.End()
+	}
+
+	body := `{"username":"johndoe","name":"John Doe","email":"` + email + `","age":30}`
+	serve(http.MethodPost, "/users/", body)
+	serve(http.MethodPost, "/users/", body) // conflict, records an exception
+	serve(http.MethodGet, "/users/johndoe", "")
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("exported %d spans, want 3", len(spans))
+	}
+	assertNoEmail(t, spans)
+
+	v, _ := attr(spans[2], "apm.user.username")
+	if !strings.HasPrefix(v.AsString(), "sha256:") {
+		t.Errorf("apm.user.username = %q, want a hash", v.AsString())
+	}
+}
+
+func TestWrapRedactsBeforeSetAttributes(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	r := redact.New(redact.Policies{
+		"apm.user.email":    {Action: redact.ActionDrop},
+		"apm.user.username": {Action: redact.ActionMask},
+	}, "")
+
+	_, span := tp.Tracer("test").Start(context.Background(), "auto-sdk")
+	wrapped := redact.Wrap(span, r)
+	wrapped.SetAttributes(attribute.String("apm.user.email", email))
+	wrapped.SetAttributes(attribute.String("apm.user.username", "johndoe"), attribute.String("apm.http.route", "/users"))
+	wrapped.End()
+
+	ended := recorder.Ended()[0]
+	got := map[attribute.Key]string{}
+	for _, kv := range ended.Attributes() {
+		got[kv.Key] = kv.Value.Emit()
+	}
+
+	if _, ok := got["apm.user.email"]; ok {
+		t.Error("dropped apm.user.email was set")
+	}
+	if got["apm.user.username"] != "j***" || got["apm.http.route"] != "/users" {
+		t.Errorf("attributes = %v", got)
+	}
+	if got["apm.redacted.keys"] != "[apm.user.email apm.user.username]" {
+		t.Errorf("apm.redacted.keys = %s, want both redacted keys", got["apm.redacted.keys"])
+	}
+
+	if redact.Wrap(span, nil) != span {
+		t.Error("Wrap with a nil redactor did not return the span")
+	}
+}
+
+func TestWrapTwiceListsAllRedactedKeys(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	r := redact.New(redact.Policies{
+		"apm.user.email":    {Action: redact.ActionDrop},
+		"apm.user.username": {Action: redact.ActionMask},
+	}, "")
+
+	// In enrich mode the instrumentation and the rules engine each wrap the
+	// borrowed active span
+	_, span := tp.Tracer("test").Start(context.Background(), "auto-sdk")
+	redact.Wrap(span, r).SetAttributes(attribute.String("apm.user.email", email))
+	redact.Wrap(span, r).SetAttributes(attribute.String("apm.user.username", "johndoe"))
+	span.End()
+
+	ended := recorder.Ended()[0]
+	for _, kv := range ended.Attributes() {
+		if kv.Key == "apm.redacted.keys" {
+			if got := kv.Value.Emit(); got != "[apm.user.email apm.user.username]" {
+				t.Errorf("apm.redacted.keys = %s, want the keys redacted by both wrappers", got)
+			}
+			return
+		}
+	}
+	t.Error("apm.redacted.keys not set")
+}
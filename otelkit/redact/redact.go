@@ -0,0 +1,233 @@
+// Package redact keeps personal data in span attributes from leaving the
+// process. A Redactor applies a policy per attribute key: drop the
+// attribute, mask or truncate its value, or replace it with a salted
+// SHA-256 hash. Redacted keys are listed in apm.redacted.keys on the span.
+//
+// With an SDK TracerProvider, NewProcessor redacts ended spans before they
+// reach the exporter. Spans owned by the eBPF Auto SDK are exported outside
+// the process, so Wrap redacts attributes before SetAttributes instead.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Action is what a policy does to an attribute
+type Action string
+
+// Policy actions
+const (
+	// ActionKeep exports the attribute unchanged
+	ActionKeep Action = "keep"
+	// ActionDrop removes the attribute
+	ActionDrop Action = "drop"
+	// ActionMask keeps the first Length characters and masks the rest
+	ActionMask Action = "mask"
+	// ActionHash replaces the value with a salted SHA-256 hash, so equal
+	// values can still be correlated
+	ActionHash Action = "hash"
+	// ActionTruncate keeps the first Length characters
+	ActionTruncate Action = "truncate"
+)
+
+// Default Length per action when a policy does not set one
+const (
+	defaultMaskLength     = 1
+	defaultTruncateLength = 8
+)
+
+// mask replaces the hidden part of a masked value
+const mask = "***"
+
+// Policy is the redaction applied to one attribute key. Mask, hash and
+// truncate apply to string and string slice values; any other value type
+// is dropped by them.
+type Policy struct {
+	Action Action
+	// Length is the number of characters kept by mask and truncate;
+	// zero keeps the action's default of 1 and 8 characters
+	Length int
+}
+
+// Policies maps attribute keys to their policy
+type Policies map[attribute.Key]Policy
+
+// DefaultPolicies hashes every attribute the apmattr catalogue classifies
+// as an identifier or personal data
+func DefaultPolicies() Policies {
+	policies := Policies{}
+	for _, def := range apmattr.All() {
+		if def.PII != apmattr.PIINone {
+			policies[def.Key] = Policy{Action: ActionHash}
+		}
+	}
+	return policies
+}
+
+// ParsePolicies parses a comma separated list of key=action[:length]
+// entries, e.g. "apm.user.email=drop,apm.user.username=mask:2"
+func ParsePolicies(spec string) (Policies, error) {
+	policies := Policies{}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, rest, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid redaction policy %q, want key=action[:length]", entry)
+		}
+
+		action, length, hasLength := strings.Cut(strings.TrimSpace(rest), ":")
+		policy := Policy{Action: Action(strings.ToLower(action))}
+
+		switch policy.Action {
+		case ActionKeep, ActionDrop, ActionHash:
+			if hasLength {
+				return nil, fmt.Errorf("redaction policy %q: %s takes no length", entry, policy.Action)
+			}
+		case ActionMask, ActionTruncate:
+			if hasLength {
+				n, err := strconv.Atoi(length)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("redaction policy %q: invalid length %q", entry, length)
+				}
+				policy.Length = n
+			}
+		default:
+			return nil, fmt.Errorf("redaction policy %q: unsupported action %q", entry, action)
+		}
+
+		policies[attribute.Key(strings.TrimSpace(key))] = policy
+	}
+
+	return policies, nil
+}
+
+// Merge returns the policies of p overridden by those of other
+func (p Policies) Merge(other Policies) Policies {
+	merged := make(Policies, len(p)+len(other))
+	for key, policy := range p {
+		merged[key] = policy
+	}
+	for key, policy := range other {
+		merged[key] = policy
+	}
+	return merged
+}
+
+// Redactor applies redaction policies to attributes
+type Redactor struct {
+	policies Policies
+	salt     []byte
+}
+
+// New creates a redactor; salt is prepended to values before hashing
+func New(policies Policies, salt string) *Redactor {
+	return &Redactor{policies: policies, salt: []byte(salt)}
+}
+
+// Redact returns attrs with the policies applied and the sorted keys that
+// were redacted. attrs is not modified; when nothing is redacted it is
+// returned as is.
+func (r *Redactor) Redact(attrs []attribute.KeyValue) ([]attribute.KeyValue, []string) {
+	var out []attribute.KeyValue
+	var redacted []string
+
+	for i, kv := range attrs {
+		policy, ok := r.policies[kv.Key]
+		if !ok || policy.Action == ActionKeep {
+			if out != nil {
+				out = append(out, kv)
+			}
+			continue
+		}
+
+		if out == nil {
+			out = make([]attribute.KeyValue, i, len(attrs))
+			copy(out, attrs[:i])
+		}
+		redacted = append(redacted, string(kv.Key))
+
+		if value, ok := r.apply(policy, kv.Value); ok {
+			out = append(out, attribute.KeyValue{Key: kv.Key, Value: value})
+		}
+	}
+
+	if out == nil {
+		return attrs, nil
+	}
+
+	sort.Strings(redacted)
+	return out, redacted
+}
+
+// apply redacts a single value; false means the attribute is dropped
+func (r *Redactor) apply(policy Policy, value attribute.Value) (attribute.Value, bool) {
+	if policy.Action == ActionDrop {
+		return attribute.Value{}, false
+	}
+
+	switch value.Type() {
+	case attribute.STRING:
+		return attribute.StringValue(r.redactString(policy, value.AsString())), true
+	case attribute.STRINGSLICE:
+		items := value.AsStringSlice()
+		for i, item := range items {
+			items[i] = r.redactString(policy, item)
+		}
+		return attribute.StringSliceValue(items), true
+	default:
+		return attribute.Value{}, false
+	}
+}
+
+// redactString applies a mask, hash or truncate policy to s
+func (r *Redactor) redactString(policy Policy, s string) string {
+	switch policy.Action {
+	case ActionHash:
+		sum := sha256.Sum256(append(append([]byte{}, r.salt...), s...))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	case ActionMask:
+		n := policy.length(defaultMaskLength)
+		if utf8.RuneCountInString(s) <= n {
+			return mask
+		}
+		return prefix(s, n) + mask
+	default:
+		return prefix(s, policy.length(defaultTruncateLength))
+	}
+}
+
+// length returns the policy length or def when it is not set
+func (p Policy) length(def int) int {
+	if p.Length > 0 {
+		return p.Length
+	}
+	return def
+}
+
+// prefix returns the first n characters of s
+func prefix(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	i := 0
+	for ; n > 0; n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return s[:i]
+}
@@ -0,0 +1,124 @@
+package redact
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestParsePolicies(t *testing.T) {
+	got, err := ParsePolicies(" apm.user.email=drop, apm.user.username=MASK:2 ,apm.db.statement=truncate,apm.user.tier=keep,,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Policies{
+		"apm.user.email":    {Action: ActionDrop},
+		"apm.user.username": {Action: ActionMask, Length: 2},
+		"apm.db.statement":  {Action: ActionTruncate},
+		"apm.user.tier":     {Action: ActionKeep},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePolicies = %v, want %v", got, want)
+	}
+}
+
+func TestParsePoliciesErrors(t *testing.T) {
+	for _, spec := range []string{"apm.user.email", "=drop", "apm.user.email=shred", "apm.user.email=hash:4", "apm.user.email=mask:-1", "apm.user.email=truncate:x"} {
+		if _, err := ParsePolicies(spec); err == nil {
+			t.Errorf("ParsePolicies(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestDefaultPolicies(t *testing.T) {
+	policies := DefaultPolicies()
+
+	for _, key := range []attribute.Key{"apm.user.email", "apm.user.username", "apm.db.query.parameter.username"} {
+		if policies[key].Action != ActionHash {
+			t.Errorf("default policy for %s = %+v, want hash", key, policies[key])
+		}
+	}
+	if _, ok := policies["apm.http.route"]; ok {
+		t.Error("apm.http.route has a default policy")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	r := New(Policies{
+		"apm.user.email":    {Action: ActionDrop},
+		"apm.user.username": {Action: ActionMask, Length: 2},
+		"apm.client.id":     {Action: ActionMask, Length: 2},
+		"apm.db.statement":  {Action: ActionTruncate, Length: 3},
+		"apm.db.filters":    {Action: ActionTruncate, Length: 1},
+		"apm.user.age":      {Action: ActionHash},
+		"apm.user.tier":     {Action: ActionKeep},
+	}, "")
+
+	attrs := []attribute.KeyValue{
+		attribute.String("apm.http.route", "unchanged"),
+		attribute.String("apm.user.email", "secret"),
+		attribute.String("apm.user.username", "john@example.com"),
+		attribute.String("apm.client.id", "jo"),
+		attribute.String("apm.db.statement", "héllo"),
+		attribute.StringSlice("apm.db.filters", []string{"ab", "cd"}),
+		attribute.Int("apm.user.age", 30),
+		attribute.String("apm.user.tier", "kept"),
+	}
+	original := append([]attribute.KeyValue(nil), attrs...)
+
+	got, redacted := r.Redact(attrs)
+
+	want := []attribute.KeyValue{
+		attribute.String("apm.http.route", "unchanged"),
+		attribute.String("apm.user.username", "jo***"),
+		attribute.String("apm.client.id", "***"),
+		attribute.String("apm.db.statement", "hél"),
+		attribute.StringSlice("apm.db.filters", []string{"a", "c"}),
+		attribute.String("apm.user.tier", "kept"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Redact = %v, want %v", got, want)
+	}
+
+	wantKeys := []string{"apm.client.id", "apm.db.filters", "apm.db.statement", "apm.user.age", "apm.user.email", "apm.user.username"}
+	if !reflect.DeepEqual(redacted, wantKeys) {
+		t.Errorf("redacted keys = %v, want %v", redacted, wantKeys)
+	}
+
+	if !reflect.DeepEqual(attrs, original) {
+		t.Error("Redact modified its input")
+	}
+}
+
+func TestRedactNothing(t *testing.T) {
+	attrs := []attribute.KeyValue{attribute.String("apm.http.route", "/users")}
+
+	got, redacted := New(DefaultPolicies(), "salt").Redact(attrs)
+	if redacted != nil || &got[0] != &attrs[0] {
+		t.Errorf("Redact = %v, %v; want the input unchanged", got, redacted)
+	}
+}
+
+func TestHashIsSaltedAndStable(t *testing.T) {
+	policies := Policies{"apm.user.email": {Action: ActionHash}}
+	email := attribute.String("apm.user.email", "john@example.com")
+
+	hash := func(salt string) string {
+		got, _ := New(policies, salt).Redact([]attribute.KeyValue{email})
+		return got[0].Value.AsString()
+	}
+
+	a, b, other := hash("salt"), hash("salt"), hash("pepper")
+	if !strings.HasPrefix(a, "sha256:") || len(a) != len("sha256:")+64 {
+		t.Errorf("hash = %q, want sha256:<64 hex digits>", a)
+	}
+	if a != b {
+		t.Error("hashing the same value twice gave different results")
+	}
+	if a == other {
+		t.Error("different salts gave the same hash")
+	}
+}
@@ -0,0 +1,106 @@
+package spantest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// update makes Golden rewrite the golden files instead of comparing them
+var update = flag.Bool("update", false, "rewrite spantest golden trace files")
+
+// Node is a span in a trace shape snapshot. Attribute values, IDs and
+// timestamps change between runs, so only attribute keys and value types
+// are kept.
+type Node struct {
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`
+	Status     string            `json:"status"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Events     []string          `json:"events,omitempty"`
+	Children   []*Node           `json:"children,omitempty"`
+}
+
+// Shape returns the recorded trace trees, roots and siblings ordered by
+// start time, then name
+func (r *Recorder) Shape() []*Node {
+	spans := r.Spans()
+	sort.SliceStable(spans, func(i, j int) bool {
+		if !spans[i].StartTime().Equal(spans[j].StartTime()) {
+			return spans[i].StartTime().Before(spans[j].StartTime())
+		}
+		return spans[i].Name() < spans[j].Name()
+	})
+
+	nodes := make(map[trace.SpanID]*Node, len(spans))
+	for _, s := range spans {
+		nodes[s.SpanContext().SpanID()] = newNode(s)
+	}
+
+	var roots []*Node
+	for _, s := range spans {
+		node := nodes[s.SpanContext().SpanID()]
+		if parent, ok := nodes[s.Parent().SpanID()]; ok && s.Parent().IsValid() {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// newNode converts a recorded span to a snapshot node without children
+func newNode(s sdktrace.ReadOnlySpan) *Node {
+	node := &Node{
+		Name:   s.Name(),
+		Kind:   s.SpanKind().String(),
+		Status: s.Status().Code.String(),
+	}
+
+	if attrs := s.Attributes(); len(attrs) > 0 {
+		node.Attributes = make(map[string]string, len(attrs))
+		for _, kv := range attrs {
+			node.Attributes[string(kv.Key)] = kv.Value.Type().String()
+		}
+	}
+	for _, e := range s.Events() {
+		node.Events = append(node.Events, e.Name)
+	}
+	return node
+}
+
+// Golden compares the recorded trace shape with the JSON golden file at
+// path, usually under testdata. Run the test with -update to rewrite it.
+func (r *Recorder) Golden(path string) {
+	r.t.Helper()
+
+	got, err := json.MarshalIndent(r.Shape(), "", "  ")
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			r.t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		r.t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		r.t.Errorf("trace shape does not match %s (run with -update to accept it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,279 @@
+// Package spantest records spans in memory so tests can assert on custom
+// attributes and trace shape.
+//
+// New installs a process-wide SDK TracerProvider as the global provider the
+// first time it is called and routes ended spans to the recorder of the
+// running test. Tracers obtained from the global provider before that, for
+// example in init functions, are delegated to it as well. Tests using a
+// Recorder must not run in parallel.
+//
+// Golden compares the whole recorded trace shape with a JSON file under
+// testdata; run the package's tests with -update to regenerate the files.
+package spantest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// provider is the global TracerProvider installed by New
+var provider struct {
+	once     sync.Once
+	tp       *sdktrace.TracerProvider
+	mu       sync.Mutex
+	recorder *tracetest.SpanRecorder
+}
+
+// router forwards span events to the recorder of the running test
+type router struct{}
+
+// OnStart forwards a started span
+func (router) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	if rec := current(); rec != nil {
+		rec.OnStart(ctx, s)
+	}
+}
+
+// OnEnd forwards an ended span
+func (router) OnEnd(s sdktrace.ReadOnlySpan) {
+	if rec := current(); rec != nil {
+		rec.OnEnd(s)
+	}
+}
+
+// Shutdown does nothing
+func (router) Shutdown(context.Context) error { return nil }
+
+// ForceFlush does nothing
+func (router) ForceFlush(context.Context) error { return nil }
+
+// current returns the recorder of the running test, if any
+func current() *tracetest.SpanRecorder {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	return provider.recorder
+}
+
+// Recorder holds the spans ended during a test
+type Recorder struct {
+	t   testing.TB
+	rec *tracetest.SpanRecorder
+}
+
+// New starts recording spans for the test t until it finishes
+func New(t testing.TB) *Recorder {
+	t.Helper()
+
+	provider.once.Do(func() {
+		provider.tp = sdktrace.NewTracerProvider(
+			sdktrace.WithSampler(sdktrace.AlwaysSample()),
+			sdktrace.WithSpanProcessor(router{}),
+		)
+		otel.SetTracerProvider(provider.tp)
+	})
+
+	r := &Recorder{t: t, rec: tracetest.NewSpanRecorder()}
+
+	provider.mu.Lock()
+	provider.recorder = r.rec
+	provider.mu.Unlock()
+
+	t.Cleanup(func() {
+		provider.mu.Lock()
+		if provider.recorder == r.rec {
+			provider.recorder = nil
+		}
+		provider.mu.Unlock()
+	})
+
+	return r
+}
+
+// Spans returns the ended spans in the order they ended
+func (r *Recorder) Spans() []sdktrace.ReadOnlySpan {
+	return r.rec.Ended()
+}
+
+// Reset discards the spans recorded so far
+func (r *Recorder) Reset() {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	r.rec = tracetest.NewSpanRecorder()
+	provider.recorder = r.rec
+}
+
+// Names returns the names of the ended spans in the order they ended
+func (r *Recorder) Names() []string {
+	spans := r.Spans()
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// Find returns the first ended span named name
+func (r *Recorder) Find(name string) (sdktrace.ReadOnlySpan, bool) {
+	for _, s := range r.Spans() {
+		if s.Name() == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Span returns assertions for the first ended span named name, failing the
+// test when there is none
+func (r *Recorder) Span(name string) *Span {
+	r.t.Helper()
+
+	s, ok := r.Find(name)
+	if !ok {
+		r.t.Fatalf("no span named %q, recorded spans: %s", name, strings.Join(r.Names(), ", "))
+	}
+	return &Span{t: r.t, r: r, span: s}
+}
+
+// NoSpan fails the test when a span named name was recorded
+func (r *Recorder) NoSpan(name string) {
+	r.t.Helper()
+
+	if _, ok := r.Find(name); ok {
+		r.t.Errorf("unexpected span %q", name)
+	}
+}
+
+// Span holds assertions on a recorded span. Every assertion returns the
+// Span so they can be chained.
+type Span struct {
+	t    testing.TB
+	r    *Recorder
+	span sdktrace.ReadOnlySpan
+}
+
+// ReadOnly returns the recorded span
+func (s *Span) ReadOnly() sdktrace.ReadOnlySpan {
+	return s.span
+}
+
+// Attribute returns the value of the attribute key
+func (s *Span) Attribute(key string) (attribute.Value, bool) {
+	for _, kv := range s.span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// HasAttribute asserts that the span has the attribute key with value.
+// value is compared with the attribute's Go value: an int matches int
+// attributes, other numbers must be int64 or float64 and slices their
+// typed slices.
+func (s *Span) HasAttribute(key string, value interface{}) *Span {
+	s.t.Helper()
+
+	if v, ok := value.(int); ok {
+		value = int64(v)
+	}
+
+	got, ok := s.Attribute(key)
+	if !ok {
+		s.t.Errorf("span %q has no attribute %s, attributes: %s", s.span.Name(), key, s.attributeKeys())
+		return s
+	}
+
+	if fmt.Sprintf("%T:%v", got.AsInterface(), got.AsInterface()) != fmt.Sprintf("%T:%v", value, value) {
+		s.t.Errorf("span %q attribute %s = %T(%v), want %T(%v)",
+			s.span.Name(), key, got.AsInterface(), got.AsInterface(), value, value)
+	}
+	return s
+}
+
+// HasAttributeKey asserts that the span has the attribute key with any value
+func (s *Span) HasAttributeKey(key string) *Span {
+	s.t.Helper()
+
+	if _, ok := s.Attribute(key); !ok {
+		s.t.Errorf("span %q has no attribute %s, attributes: %s", s.span.Name(), key, s.attributeKeys())
+	}
+	return s
+}
+
+// NoAttribute asserts that the span does not have the attribute key
+func (s *Span) NoAttribute(key string) *Span {
+	s.t.Helper()
+
+	if v, ok := s.Attribute(key); ok {
+		s.t.Errorf("span %q has unexpected attribute %s = %s", s.span.Name(), key, v.Emit())
+	}
+	return s
+}
+
+// ChildOf asserts that the span's parent is the first span named parent
+func (s *Span) ChildOf(parent string) *Span {
+	s.t.Helper()
+
+	p, ok := s.r.Find(parent)
+	if !ok {
+		s.t.Errorf("span %q: no parent span named %q", s.span.Name(), parent)
+		return s
+	}
+
+	if s.span.Parent().SpanID() != p.SpanContext().SpanID() {
+		s.t.Errorf("span %q is not a child of %q", s.span.Name(), parent)
+	}
+	return s
+}
+
+// IsRoot asserts that the span has no parent
+func (s *Span) IsRoot() *Span {
+	s.t.Helper()
+
+	if s.span.Parent().IsValid() {
+		s.t.Errorf("span %q has a parent, want a root span", s.span.Name())
+	}
+	return s
+}
+
+// HasStatus asserts the span status code
+func (s *Span) HasStatus(code codes.Code) *Span {
+	s.t.Helper()
+
+	if got := s.span.Status().Code; got != code {
+		s.t.Errorf("span %q status = %s, want %s", s.span.Name(), got, code)
+	}
+	return s
+}
+
+// HasEvent asserts that the span has an event named name, e.g. "exception"
+func (s *Span) HasEvent(name string) *Span {
+	s.t.Helper()
+
+	for _, e := range s.span.Events() {
+		if e.Name == name {
+			return s
+		}
+	}
+	s.t.Errorf("span %q has no %q event", s.span.Name(), name)
+	return s
+}
+
+// attributeKeys lists the span's attribute keys for failure messages
+func (s *Span) attributeKeys() string {
+	keys := make([]string, 0, len(s.span.Attributes()))
+	for _, kv := range s.span.Attributes() {
+		keys = append(keys, string(kv.Key))
+	}
+	return strings.Join(keys, ", ")
+}
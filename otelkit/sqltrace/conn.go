@@ -0,0 +1,273 @@
+package sqltrace
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+)
+
+// Operations of the calls that do not run a statement
+const (
+	opPrepare  = "PREPARE"
+	opBegin    = "BEGIN"
+	opCommit   = "COMMIT"
+	opRollback = "ROLLBACK"
+)
+
+// conn is an instrumented connection. It implements the context interfaces
+// of database/sql and falls back to the plain driver.Conn methods when the
+// wrapped connection does not.
+type conn struct {
+	driver.Conn
+	tracer *tracer
+}
+
+var (
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.Validator          = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+)
+
+// Prepare prepares a traced statement
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext prepares a statement within a PREPARE span; the statement
+// traces its own executions
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	statement := Normalize(query)
+	ctx, call := c.tracer.start(ctx, opPrepare, opPrepare, &statement)
+
+	var s driver.Stmt
+	var err error
+	if cp, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err = cp.PrepareContext(ctx, query)
+	} else {
+		s, err = c.Conn.Prepare(query)
+	}
+	call.end(err)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: s, conn: c, statement: statement, tracer: c.tracer}, nil
+}
+
+// Begin starts a traced transaction
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts a transaction within a BEGIN span
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	spanCtx, call := c.tracer.start(ctx, opBegin, opBegin, nil)
+
+	var dtx driver.Tx
+	var err error
+	if cb, ok := c.Conn.(driver.ConnBeginTx); ok {
+		dtx, err = cb.BeginTx(spanCtx, opts)
+	} else {
+		dtx, err = beginLegacy(c.Conn, opts)
+	}
+	call.end(err)
+	if err != nil {
+		return nil, err
+	}
+	// Commit and Rollback take no context; their spans are parented to the
+	// caller of BeginTx
+	return &tx{Tx: dtx, ctx: ctx, tracer: c.tracer}, nil
+}
+
+// beginLegacy starts a transaction on a connection without
+// driver.ConnBeginTx, which cannot apply options; like database/sql, it
+// rejects options other than the defaults rather than ignore them
+func beginLegacy(c driver.Conn, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sqltrace: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sqltrace: driver does not support read-only transactions")
+	}
+	return c.Begin()
+}
+
+// QueryContext runs a query within a span named after its operation
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		// database/sql prepares a statement instead
+		return nil, driver.ErrSkip
+	}
+
+	ctx, call := c.tracer.startStatement(ctx, Normalize(query), "QUERY")
+	rows, err := q.QueryContext(ctx, query, args)
+	call.end(err)
+	return rows, err
+}
+
+// ExecContext runs a statement within a span named after its operation
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		// database/sql prepares a statement instead
+		return nil, driver.ErrSkip
+	}
+
+	ctx, call := c.tracer.startStatement(ctx, Normalize(query), "EXEC")
+	res, err := e.ExecContext(ctx, query, args)
+	call.endResult(res, err)
+	return res, err
+}
+
+// Ping checks the connection; it is not traced
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession resets the wrapped connection before it is reused
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid reports whether the wrapped connection may be reused
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue lets the wrapped connection convert arguments;
+// driver.ErrSkip makes database/sql use its default conversion
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// stmt is an instrumented prepared statement
+type stmt struct {
+	driver.Stmt
+	conn      *conn
+	statement Statement
+	tracer    *tracer
+}
+
+var (
+	_ driver.StmtQueryContext  = (*stmt)(nil)
+	_ driver.StmtExecContext   = (*stmt)(nil)
+	_ driver.NamedValueChecker = (*stmt)(nil)
+)
+
+// Exec executes the statement
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+// Query runs the statement
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+// ExecContext executes the statement within a span named after its operation
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, call := s.tracer.startStatement(ctx, s.statement, "EXEC")
+
+	var res driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = driverValues(args); err == nil {
+			res, err = s.Stmt.Exec(values)
+		}
+	}
+	call.endResult(res, err)
+	return res, err
+}
+
+// QueryContext runs the statement within a span named after its operation
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, call := s.tracer.startStatement(ctx, s.statement, "QUERY")
+
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = driverValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	call.end(err)
+	return rows, err
+}
+
+// CheckNamedValue lets the wrapped statement, or else its connection,
+// convert arguments
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+// tx is an instrumented transaction
+type tx struct {
+	driver.Tx
+	ctx    context.Context
+	tracer *tracer
+}
+
+// Commit commits the transaction within a COMMIT span
+func (t *tx) Commit() error {
+	_, call := t.tracer.start(t.ctx, opCommit, opCommit, nil)
+	err := t.Tx.Commit()
+	call.end(err)
+	return err
+}
+
+// Rollback aborts the transaction within a ROLLBACK span
+func (t *tx) Rollback() error {
+	_, call := t.tracer.start(t.ctx, opRollback, opRollback, nil)
+	err := t.Tx.Rollback()
+	call.end(err)
+	return err
+}
+
+// namedValues converts positional arguments to named values
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+// driverValues converts named values to positional arguments for drivers
+// without the context interfaces, which do not support names
+func driverValues(named []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(named))
+	for i, nv := range named {
+		if nv.Name != "" {
+			return nil, errors.New("sqltrace: driver does not support named parameters")
+		}
+		values[i] = nv.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,69 @@
+package sqltrace
+
+import (
+	"context"
+	"database/sql"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// RegisterPoolMetrics reports the connection pool statistics of db
+// (sql.DBStats) as metrics of the global MeterProvider. The instruments are
+// observed once per collection, so the reader of the MeterProvider sets how
+// often the pool is sampled. The open connections are the sum of
+// db.client.connections.usage over its used and idle states.
+//
+// Call Unregister on the returned registration before closing db.
+func RegisterPoolMetrics(db *sql.DB, poolName string, cfg Config) (metric.Registration, error) {
+	name := cfg.TracerName
+	if name == "" {
+		name = defaultTracerName
+	}
+	meter := otel.Meter(name)
+
+	usage, err := meter.Int64ObservableUpDownCounter("db.client.connections.usage",
+		metric.WithUnit("{connection}"),
+		metric.WithDescription("Number of connections that are currently in the state described by the state attribute"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	max, err := meter.Int64ObservableUpDownCounter("db.client.connections.max",
+		metric.WithUnit("{connection}"),
+		metric.WithDescription("Maximum number of open connections allowed, 0 when unlimited"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	waitCount, err := meter.Int64ObservableCounter("db.client.connections.wait_count",
+		metric.WithUnit("{wait}"),
+		metric.WithDescription("Number of times a call waited for a free connection"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	waitDuration, err := meter.Float64ObservableCounter("db.client.connections.wait_duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Total time calls waited for a free connection"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := attribute.NewSet(semconv.PoolName(poolName), semconv.DBSystemKey.String(cfg.System))
+	used := attribute.NewSet(semconv.PoolName(poolName), semconv.DBSystemKey.String(cfg.System), semconv.StateUsed)
+	idle := attribute.NewSet(semconv.PoolName(poolName), semconv.DBSystemKey.String(cfg.System), semconv.StateIdle)
+
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := db.Stats()
+		o.ObserveInt64(usage, int64(stats.InUse), metric.WithAttributeSet(used))
+		o.ObserveInt64(usage, int64(stats.Idle), metric.WithAttributeSet(idle))
+		o.ObserveInt64(max, int64(stats.MaxOpenConnections), metric.WithAttributeSet(pool))
+		o.ObserveInt64(waitCount, stats.WaitCount, metric.WithAttributeSet(pool))
+		o.ObserveFloat64(waitDuration, stats.WaitDuration.Seconds(), metric.WithAttributeSet(pool))
+		return nil
+	}, usage, max, waitCount, waitDuration)
+}
@@ -0,0 +1,75 @@
+package sqltrace
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+func TestPoolMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	db := openTestDB(t)
+	db.SetMaxOpenConns(4)
+	reg, err := RegisterPoolMetrics(db, "users", Config{TracerName: "test", System: "sqlite"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reg.Unregister()
+
+	// Hold one connection while the one used by openTestDB stays idle
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.PingContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					name := m.Name
+					if state, ok := dp.Attributes.Value(semconv.StateKey); ok {
+						name += "." + state.AsString()
+					}
+					if pool, _ := dp.Attributes.Value(semconv.PoolNameKey); pool.AsString() != "users" {
+						t.Errorf("%s pool.name = %q, want users", m.Name, pool.AsString())
+					}
+					got[name] = dp.Value
+				}
+			case metricdata.Sum[float64]:
+				got[m.Name] = int64(len(data.DataPoints))
+			}
+		}
+	}
+
+	want := map[string]int64{
+		"db.client.connections.usage.used":    1,
+		"db.client.connections.max":           4,
+		"db.client.connections.wait_count":    0,
+		"db.client.connections.wait_duration": 1,
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %d, want %d (got %v)", name, got[name], value, got)
+		}
+	}
+	if _, ok := got["db.client.connections.usage.idle"]; !ok {
+		t.Errorf("no idle connection usage reported, got %v", got)
+	}
+}
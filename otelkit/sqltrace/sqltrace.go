@@ -0,0 +1,212 @@
+// Package sqltrace instruments database/sql at the driver level. The
+// wrapped driver.Connector starts a client span for every query, exec,
+// prepare, begin, commit and rollback with the database semantic convention
+// attributes, the normalized statement and its fingerprint (see Normalize),
+// the rows affected and the driver latency, so code using the *sql.DB does
+// not have to trace its statements. RegisterPoolMetrics reports the
+// connection pool statistics as metrics.
+package sqltrace
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"otelkit/apmattr"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultTracerName names the tracer when Config.TracerName is empty
+const defaultTracerName = "sqltrace"
+
+// Config configures the instrumentation
+type Config struct {
+	// TracerName names the tracer of the client spans and the meter of the
+	// pool metrics
+	TracerName string
+	// System is reported as db.system, e.g. postgresql or sqlite
+	System string
+}
+
+// Open opens a database with the registered driver driverName, like
+// sql.Open, with every connection instrumented
+func Open(driverName, dsn string, cfg Config) (*sql.DB, error) {
+	// sql.Open only looks the driver up, it does not connect
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	db.Close()
+
+	if dc, ok := d.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return sql.OpenDB(WrapConnector(connector, cfg)), nil
+	}
+	return sql.OpenDB(WrapConnector(dsnConnector{dsn: dsn, driver: d}, cfg)), nil
+}
+
+// WrapConnector returns a connector whose connections are instrumented
+func WrapConnector(c driver.Connector, cfg Config) driver.Connector {
+	return &connector{Connector: c, tracer: newTracer(cfg)}
+}
+
+// Wrap returns a driver whose connections are instrumented, for
+// registering with sql.Register
+func Wrap(d driver.Driver, cfg Config) driver.Driver {
+	return &wrappedDriver{Driver: d, tracer: newTracer(cfg)}
+}
+
+// wrappedDriver instruments the connections of a driver
+type wrappedDriver struct {
+	driver.Driver
+	tracer *tracer
+}
+
+// Open opens an instrumented connection
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, tracer: d.tracer}, nil
+}
+
+// OpenConnector returns an instrumented connector for name
+func (d *wrappedDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.Driver.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &connector{Connector: c, tracer: d.tracer}, nil
+	}
+	return &connector{Connector: dsnConnector{dsn: name, driver: d.Driver}, tracer: d.tracer}, nil
+}
+
+// connector instruments the connections of a connector
+type connector struct {
+	driver.Connector
+	tracer *tracer
+}
+
+// Connect opens an instrumented connection
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc, tracer: c.tracer}, nil
+}
+
+// Driver returns the instrumented driver
+func (c *connector) Driver() driver.Driver {
+	return &wrappedDriver{Driver: c.Connector.Driver(), tracer: c.tracer}
+}
+
+// dsnConnector is the connector of a driver without driver.DriverContext
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+// Connect opens a connection to the data source name
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver returns the driver
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// tracer starts the client spans of one database
+type tracer struct {
+	tracer trace.Tracer
+	system string
+}
+
+// newTracer creates the tracer for cfg
+func newTracer(cfg Config) *tracer {
+	name := cfg.TracerName
+	if name == "" {
+		name = defaultTracerName
+	}
+	return &tracer{tracer: otel.Tracer(name), system: cfg.System}
+}
+
+// call is a traced driver call
+type call struct {
+	span  trace.Span
+	start time.Time
+}
+
+// start starts the client span of a call. s is the statement the call
+// runs or prepares, nil for the calls that run none.
+func (t *tracer) start(ctx context.Context, name, op string, s *Statement) (context.Context, *call) {
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemKey.String(t.system),
+		semconv.DBOperation(op),
+	}
+	if s != nil {
+		attrs = append(attrs,
+			semconv.DBStatement(s.Text),
+			apmattr.DBStatementFingerprint.String(s.Fingerprint),
+		)
+		if s.Table != "" {
+			attrs = append(attrs, semconv.DBSQLTable(s.Table))
+		}
+	}
+
+	ctx, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, &call{span: span, start: time.Now()}
+}
+
+// startStatement starts the client span of a statement execution, named
+// after its operation and table, e.g. SELECT go_user_tbl. def is the
+// operation of a statement without a keyword.
+func (t *tracer) startStatement(ctx context.Context, s Statement, def string) (context.Context, *call) {
+	op := s.Operation
+	if op == "" {
+		op = def
+	}
+	name := op
+	if s.Table != "" {
+		name += " " + s.Table
+	}
+	return t.start(ctx, name, op, &s)
+}
+
+// end records the latency and the error of the call and ends its span.
+// driver.ErrSkip is not a failure, database/sql falls back to another call.
+func (c *call) end(err error) {
+	c.span.SetAttributes(apmattr.DBDurationMS.Float64(float64(time.Since(c.start)) / float64(time.Millisecond)))
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		c.span.RecordError(err)
+		c.span.SetStatus(codes.Error, err.Error())
+	}
+	c.span.End()
+}
+
+// endResult records the rows affected by an exec, then ends the call
+func (c *call) endResult(res driver.Result, err error) {
+	if err == nil && res != nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			c.span.SetAttributes(apmattr.DBRowsAffected.Int64(n))
+		}
+	}
+	c.end(err)
+}
@@ -0,0 +1,207 @@
+package sqltrace
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"otelkit/spantest"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// openTestDB opens an instrumented SQLite database with a users table
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "test.db"), Config{TracerName: "test", System: "sqlite"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`CREATE TABLE users (username TEXT PRIMARY KEY, age INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestStatementSpans(t *testing.T) {
+	db := openTestDB(t)
+	rec := spantest.New(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "repository")
+	if _, err := db.ExecContext(ctx, `INSERT INTO users (username, age) VALUES ('johndoe', 30), ($1, $2)`, "janedoe", 28); err != nil {
+		t.Fatal(err)
+	}
+	var age int
+	if err := db.QueryRowContext(ctx, `SELECT age FROM users WHERE username = $1`, "janedoe").Scan(&age); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	rec.Span("INSERT users").
+		ChildOf("repository").
+		HasAttribute("db.system", "sqlite").
+		HasAttribute("db.operation", "INSERT").
+		HasAttribute("db.statement", `INSERT INTO users (username, age) VALUES (?, ?), (?, ?)`).
+		HasAttribute("db.sql.table", "users").
+		HasAttribute("apm.db.statement.fingerprint", Normalize(`INSERT INTO users (username, age) VALUES (?, ?), (?, ?)`).Fingerprint).
+		HasAttribute("apm.db.rows_affected", 2).
+		HasAttributeKey("apm.db.duration_ms").
+		HasStatus(codes.Unset)
+	rec.Span("SELECT users").
+		ChildOf("repository").
+		HasAttribute("db.statement", `SELECT age FROM users WHERE username = ?`).
+		NoAttribute("apm.db.rows_affected")
+}
+
+func TestTransactionAndPrepareSpans(t *testing.T) {
+	db := openTestDB(t)
+	rec := spantest.New(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "repository")
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := tx.PrepareContext(ctx, `DELETE FROM users WHERE age > 40`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	rec.Span("BEGIN").ChildOf("repository").NoAttribute("db.statement")
+	rec.Span("PREPARE").ChildOf("repository").HasAttribute("db.statement", `DELETE FROM users WHERE age > ?`)
+	rec.Span("DELETE users").ChildOf("repository").HasAttribute("apm.db.rows_affected", 0)
+	rec.Span("ROLLBACK").ChildOf("repository").HasAttribute("db.operation", "ROLLBACK")
+}
+
+func TestFailedStatement(t *testing.T) {
+	db := openTestDB(t)
+	rec := spantest.New(t)
+
+	if _, err := db.Exec(`UPDATE missing SET age = 1`); err == nil {
+		t.Fatal("Exec on a missing table succeeded")
+	}
+
+	rec.Span("UPDATE missing").
+		HasStatus(codes.Error).
+		HasEvent("exception")
+}
+
+// legacyDriver opens connections that only implement driver.Conn
+type legacyDriver struct{}
+
+func (legacyDriver) Open(string) (driver.Conn, error) { return legacyConn{}, nil }
+
+type legacyConn struct{}
+
+func (legacyConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (legacyConn) Close() error                        { return nil }
+func (legacyConn) Begin() (driver.Tx, error)           { return legacyTx{}, nil }
+
+type legacyTx struct{}
+
+func (legacyTx) Commit() error   { return nil }
+func (legacyTx) Rollback() error { return nil }
+
+func TestBeginTxOptionsWithoutConnBeginTx(t *testing.T) {
+	db := sql.OpenDB(WrapConnector(dsnConnector{driver: legacyDriver{}}, Config{System: "legacy"}))
+	defer db.Close()
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx with default options: %v", err)
+	}
+	tx.Rollback()
+
+	for _, opts := range []*sql.TxOptions{{Isolation: sql.LevelSerializable}, {ReadOnly: true}} {
+		if _, err := db.BeginTx(ctx, opts); err == nil {
+			t.Errorf("BeginTx(%+v) succeeded on a driver that cannot apply the options", *opts)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Statement
+	}{
+		{
+			"\n\t\tSELECT username, age\n\t\tFROM go_user_tbl\n\t\tWHERE username = $1\n\t",
+			Statement{Text: "SELECT username, age FROM go_user_tbl WHERE username = ?", Operation: "SELECT", Table: "go_user_tbl"},
+		},
+		{
+			`select * from users where name = 'john''s' and age > 30.5 -- adults`,
+			Statement{Text: "select * from users where name = ? and age > ?", Operation: "SELECT", Table: "users"},
+		},
+		{
+			`DELETE FROM "Users" WHERE id IN (1, 2, 3) /* batch */`,
+			Statement{Text: `DELETE FROM "Users" WHERE id IN (?)`, Operation: "DELETE", Table: "Users"},
+		},
+		{
+			`INSERT INTO go_user_tbl2 (name, age) VALUES (:name, ?1)`,
+			Statement{Text: "INSERT INTO go_user_tbl2 (name, age) VALUES (?, ?)", Operation: "INSERT", Table: "go_user_tbl2"},
+		},
+		{
+			`UPDATE public.users SET tags = tags::text WHERE id = ?`,
+			Statement{Text: "UPDATE public.users SET tags = tags::text WHERE id = ?", Operation: "UPDATE", Table: "public.users"},
+		},
+		{
+			`SELECT COUNT(*) FROM (SELECT 1) AS t`,
+			Statement{Text: "SELECT COUNT(*) FROM (SELECT ?) AS t", Operation: "SELECT"},
+		},
+		{
+			`SELECT * FROM users WHERE bio = $$it's a secret$$ OR bio = $body$ $$nested$$ $body$ AND id = $1`,
+			Statement{Text: "SELECT * FROM users WHERE bio = ? OR bio = ? AND id = ?", Operation: "SELECT", Table: "users"},
+		},
+		{
+			`UPDATE users SET note = E'it\'s \\ secret', alt = e'a''b' WHERE name = 'x'`,
+			Statement{Text: "UPDATE users SET note = ?, alt = ? WHERE name = ?", Operation: "UPDATE", Table: "users"},
+		},
+		{
+			`SELECT price$usd FROM items WHERE note = $$unterminated secret`,
+			Statement{Text: "SELECT price$usd FROM items WHERE note = ?", Operation: "SELECT", Table: "items"},
+		},
+		{
+			`CREATE TABLE t (id INTEGER)`,
+			Statement{Text: "CREATE TABLE t (id INTEGER)", Operation: "CREATE"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := Normalize(tt.query)
+		got.Fingerprint = ""
+		if got != tt.want {
+			t.Errorf("Normalize(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	same := []string{
+		`SELECT * FROM users WHERE id IN ($1, $2) AND name = 'a'`,
+		"select *\n  from users\n  where id in (?1) and name = 'b' -- other values",
+	}
+	if a, b := Normalize(same[0]).Fingerprint, Normalize(same[1]).Fingerprint; a != b {
+		t.Errorf("fingerprints of the same shape differ: %s, %s", a, b)
+	}
+
+	if a, b := Normalize(`SELECT * FROM users`).Fingerprint, Normalize(`SELECT * FROM groups`).Fingerprint; a == b {
+		t.Errorf("fingerprints of different statements are both %s", a)
+	}
+}
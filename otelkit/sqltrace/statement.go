@@ -0,0 +1,229 @@
+package sqltrace
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Statement is a normalized SQL statement, safe to record on spans
+type Statement struct {
+	// Text is the statement with its literals and bound parameters
+	// replaced by ?, comments removed, whitespace collapsed and IN lists
+	// reduced to IN (?)
+	Text string
+	// Operation is the upper cased first keyword, e.g. SELECT; empty when
+	// the statement has none
+	Operation string
+	// Table is the table the statement reads from or writes to; empty when
+	// it cannot be derived
+	Table string
+	// Fingerprint identifies the shape of the statement: statements that
+	// differ only in values, whitespace, comments, keyword case or IN list
+	// length share it
+	Fingerprint string
+}
+
+// Normalize normalizes query
+func Normalize(query string) Statement {
+	text := inListPattern.ReplaceAllString(normalizeText(query), "IN (?)")
+	op := operation(text)
+
+	h := fnv.New64a()
+	h.Write([]byte(strings.ToLower(text)))
+
+	return Statement{
+		Text:        text,
+		Operation:   op,
+		Table:       table(text, op),
+		Fingerprint: fmt.Sprintf("%016x", h.Sum64()),
+	}
+}
+
+// inListPattern matches an IN list of normalized values
+var inListPattern = regexp.MustCompile(`(?i)\bIN ?\(\?(?:, ?\?)*\)`)
+
+// normalizeText replaces literals and bound parameters ($1, ?1, ?, :name)
+// with ?, drops comments and collapses whitespace. Quoted identifiers are
+// kept as they are.
+func normalizeText(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	space := false
+	var last byte
+	emit := func(s string) {
+		if space && b.Len() > 0 && last != '(' && s[0] != ')' && s[0] != ',' {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s)
+		last = s[len(s)-1]
+		space = false
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			space = true
+			i++
+		case strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+			space = true
+		case strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(query)
+			}
+			space = true
+		case c == '\'':
+			i = skipQuoted(query, i, '\'')
+			emit("?")
+		case (c == 'E' || c == 'e') && i+1 < len(query) && query[i+1] == '\'' && !continuesWord(query, i):
+			// PostgreSQL escape string, E'it\'s'
+			i = skipEscaped(query, i+1)
+			emit("?")
+		case c == '$' && !continuesWord(query, i) && dollarTagPattern.MatchString(query[i:]):
+			// PostgreSQL dollar-quoted string, $$...$$ or $tag$...$tag$
+			tag := dollarTagPattern.FindString(query[i:])
+			if end := strings.Index(query[i+len(tag):], tag); end >= 0 {
+				i += len(tag) + end + len(tag)
+			} else {
+				i = len(query)
+			}
+			emit("?")
+		case c == '"' || c == '`':
+			end := skipQuoted(query, i, c)
+			emit(query[i:end])
+			i = end
+		case isDigit(c) && !continuesWord(query, i):
+			for i < len(query) && (isWordByte(query[i]) || query[i] == '.') {
+				i++
+			}
+			emit("?")
+		case c == '?' || c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			for i++; i < len(query) && isDigit(query[i]); i++ {
+			}
+			emit("?")
+		case c == ':' && i+1 < len(query) && isWordStart(query[i+1]) && (i == 0 || query[i-1] != ':'):
+			for i++; i < len(query) && isWordByte(query[i]); i++ {
+			}
+			emit("?")
+		default:
+			emit(query[i : i+1])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// skipQuoted returns the index after the quoted token starting at i; a
+// doubled quote is an escaped quote
+func skipQuoted(query string, i int, quote byte) int {
+	for i++; i < len(query); i++ {
+		if query[i] != quote {
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(query)
+}
+
+// skipEscaped returns the index after the escape string literal whose
+// opening quote is at i; a backslash escapes the next byte and a doubled
+// quote is an escaped quote
+func skipEscaped(query string, i int) int {
+	for i++; i < len(query); i++ {
+		switch {
+		case query[i] == '\\':
+			i++
+		case query[i] != '\'':
+		case i+1 < len(query) && query[i+1] == '\'':
+			i++
+		default:
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// dollarTagPattern matches a dollar quote delimiter; the tag follows the
+// identifier rules, so $1 stays a placeholder
+var dollarTagPattern = regexp.MustCompile(`^\$(?:[A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// continuesWord reports whether the digit at i is part of an identifier
+// rather than a numeric literal
+func continuesWord(query string, i int) bool {
+	return i > 0 && (isWordByte(query[i-1]) || query[i-1] >= 0x80)
+}
+
+// isDigit reports whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isWordStart reports whether c may start an identifier
+func isWordStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isWordByte reports whether c may continue an identifier
+func isWordByte(c byte) bool {
+	return isWordStart(c) || isDigit(c)
+}
+
+// operation returns the upper cased first keyword of a normalized text
+func operation(text string) string {
+	text = strings.TrimLeft(text, "( ")
+	end := strings.IndexFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if end < 0 {
+		end = len(text)
+	}
+	return strings.ToUpper(text[:end])
+}
+
+// tableClauses maps operations to the keyword their table follows
+var tableClauses = map[string]string{
+	"SELECT":  "FROM",
+	"DELETE":  "FROM",
+	"INSERT":  "INTO",
+	"REPLACE": "INTO",
+	"UPDATE":  "UPDATE",
+}
+
+// table returns the first table named after the clause keyword of op in a
+// normalized text; empty when it is a subquery or there is none
+func table(text, op string) string {
+	clause, ok := tableClauses[op]
+	if !ok {
+		return ""
+	}
+
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ' ' || r == '(' || r == ')' || r == ',' || r == ';'
+	})
+	for i, w := range words {
+		if !strings.EqualFold(w, clause) || i+1 >= len(words) {
+			continue
+		}
+		name := words[i+1]
+		if strings.EqualFold(name, "SELECT") || name == "?" {
+			return ""
+		}
+		return strings.NewReplacer(`"`, "", "`", "").Replace(name)
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BaggageMiddleware extracts the W3C baggage header into the request
+// context, so it propagates to child spans and outbound calls, and promotes
+// the allow-listed members configured by Setup onto the active span
+func BaggageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.Baggage{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		state.mu.RLock()
+		allow := state.baggage
+		state.mu.RUnlock()
+
+		if attrs := allow.Attributes(ctx); len(attrs) > 0 {
+			WrapSpan(trace.SpanFromContext(ctx)).SetAttributes(attrs...)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// PromoteBaggage sets the allow-listed baggage members of ctx on span, a
+// span just started from ctx. With the in-process SDK the baggage span
+// processor promotes them on every span, so it does nothing.
+func PromoteBaggage(ctx context.Context, span trace.Span) {
+	state.mu.RLock()
+	hooks := state.hooks
+	state.mu.RUnlock()
+
+	if hooks == nil {
+		return
+	}
+	if attrs := hooks.baggage.Attributes(ctx); len(attrs) > 0 {
+		WrapSpan(span).SetAttributes(attrs...)
+	}
+}
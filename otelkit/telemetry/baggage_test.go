@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"otelkit/baggageattr"
+
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// setBaggageHooks configures the baggage allowlist as Setup does in auto mode
+func setBaggageHooks(t *testing.T) {
+	t.Helper()
+
+	allow, err := baggageattr.ParseAllowlist(baggageattr.DefaultAllowlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state.mu.Lock()
+	state.baggage = allow
+	state.mu.Unlock()
+	setSpanHooks(&spanHooks{baggage: allow})
+
+	t.Cleanup(func() {
+		state.mu.Lock()
+		state.baggage = nil
+		state.mu.Unlock()
+		setSpanHooks(nil)
+	})
+}
+
+// tenant returns the apm.tenant.id attribute of an ended span
+func tenant(s sdktrace.ReadOnlySpan) string {
+	for _, kv := range s.Attributes() {
+		if kv.Key == "apm.tenant.id" {
+			return kv.Value.AsString()
+		}
+	}
+	return ""
+}
+
+func TestBaggageMiddleware(t *testing.T) {
+	setBaggageHooks(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	var got baggage.Baggage
+	handler := BaggageMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = baggage.FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("baggage", "tenant.id=acme,session=s3cr3t")
+	ctx, span := tracer.Start(req.Context(), "server")
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	span.End()
+
+	if got.Member("session").Value() != "s3cr3t" {
+		t.Errorf("handler baggage = %s, want the request baggage", got)
+	}
+	if got := tenant(recorder.Ended()[0]); got != "acme" {
+		t.Errorf("server span apm.tenant.id = %q, want acme", got)
+	}
+	for _, kv := range recorder.Ended()[0].Attributes() {
+		if kv.Value.AsString() == "s3cr3t" {
+			t.Errorf("member not in the allowlist promoted to %s", kv.Key)
+		}
+	}
+}
+
+func TestPromoteBaggage(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	bag, _ := baggage.Parse("tenant.id=acme")
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	start := func() {
+		ctx, span := tracer.Start(ctx, "call_external_api")
+		PromoteBaggage(ctx, span)
+		span.End()
+	}
+
+	// Without hooks, as in SDK mode, the span processor promotes baggage
+	start()
+	setBaggageHooks(t)
+	start()
+
+	ended := recorder.Ended()
+	if got := tenant(ended[0]); got != "" {
+		t.Errorf("apm.tenant.id = %q without hooks, want none", got)
+	}
+	if got := tenant(ended[1]); got != "acme" {
+		t.Errorf("apm.tenant.id = %q, want acme", got)
+	}
+}
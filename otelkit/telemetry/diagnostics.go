@@ -0,0 +1,247 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"otelkit/attrlimit"
+	"otelkit/baggageattr"
+	"otelkit/env"
+	"otelkit/redact"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMode is the tracing implementation detected at runtime
+type TracingMode string
+
+// Detected tracing modes
+const (
+	// TracingAutoSDK means eBPF auto-instrumentation owns the global tracer
+	TracingAutoSDK TracingMode = "auto_sdk"
+	// TracingSDK means an in-process SDK TracerProvider is installed
+	TracingSDK TracingMode = "sdk"
+	// TracingNoop means spans are neither recorded nor exported
+	TracingNoop TracingMode = "noop"
+)
+
+// Detect determines which tracing implementation is active. An installed
+// SDK TracerProvider is recognised directly; otherwise a probe span tells
+// the Auto SDK (valid span context) apart from the no-op tracer.
+func Detect(ctx context.Context) TracingMode {
+	if _, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); ok {
+		return TracingSDK
+	}
+
+	if AutoSDKDetected(ctx) {
+		return TracingAutoSDK
+	}
+
+	return TracingNoop
+}
+
+// TracerStats holds span counts for a single tracer
+type TracerStats struct {
+	Started int64 `json:"started"`
+	Ended   int64 `json:"ended"`
+}
+
+// Diagnostics is the tracing state reported by the /debug/telemetry endpoint
+type Diagnostics struct {
+	Mode                TracingMode            `json:"mode"`
+	ConfiguredMode      string                 `json:"configured_mode"`
+	ServiceName         string                 `json:"service_name"`
+	Exporter            string                 `json:"exporter,omitempty"`
+	Sampler             string                 `json:"sampler"`
+	Tracers             map[string]TracerStats `json:"tracers"`
+	TracerNames         []string               `json:"tracer_names"`
+	EnrichmentFallbacks int64                  `json:"enrichment_fallbacks"`
+	AttributeLimits     attrlimit.Stats        `json:"attribute_limits"`
+	StartedAt           time.Time              `json:"started_at"`
+}
+
+// state is the process-wide telemetry state populated by Setup
+var state = struct {
+	mu        sync.RWMutex
+	cfg       Config
+	detected  TracingMode
+	exporter  string
+	startedAt time.Time
+	counter   *spanCounter
+	limiter   *attrlimit.Limiter
+	baggage   baggageattr.Allowlist
+	hooks     *spanHooks
+	fallbacks atomic.Int64
+}{startedAt: time.Now()}
+
+// setDetected caches the tracing mode detected at startup
+func setDetected(mode TracingMode) {
+	state.mu.Lock()
+	state.detected = mode
+	state.mu.Unlock()
+}
+
+// detectedMode returns the tracing mode cached by Setup, detecting and
+// caching it on first use when Setup was not called
+func detectedMode(ctx context.Context) TracingMode {
+	state.mu.RLock()
+	mode := state.detected
+	state.mu.RUnlock()
+
+	if mode == "" {
+		mode = Detect(ctx)
+		setDetected(mode)
+	}
+	return mode
+}
+
+// spanHooks are applied by WrapSpan and PromoteBaggage
+type spanHooks struct {
+	redactor *redact.Redactor
+	limiter  *attrlimit.Limiter
+	baggage  baggageattr.Allowlist
+}
+
+// setSpanHooks installs the hooks applied by WrapSpan
+func setSpanHooks(hooks *spanHooks) {
+	state.mu.Lock()
+	state.hooks = hooks
+	state.mu.Unlock()
+}
+
+// WrapSpan returns span with PII redaction and attribute limits applied to
+// SetAttributes. The hooks are only installed when spans are not exported
+// by the in-process SDK, whose span processors apply them instead;
+// otherwise span is returned as is.
+func WrapSpan(span trace.Span) trace.Span {
+	state.mu.RLock()
+	hooks := state.hooks
+	state.mu.RUnlock()
+
+	if hooks == nil {
+		return span
+	}
+	// Redaction runs first, so the limits apply to the redacted values
+	return redact.Wrap(attrlimit.Wrap(span, hooks.limiter), hooks.redactor)
+}
+
+// NoteEnrichmentFallback records that a handler started a child span
+// because the active span was not recording
+func NoteEnrichmentFallback() {
+	state.fallbacks.Add(1)
+}
+
+// CurrentDiagnostics returns a snapshot of the tracing state
+func CurrentDiagnostics(ctx context.Context) Diagnostics {
+	state.mu.RLock()
+	cfg, exporter, counter, limiter := state.cfg, state.exporter, state.counter, state.limiter
+	state.mu.RUnlock()
+
+	d := Diagnostics{
+		Mode:                detectedMode(ctx),
+		ConfiguredMode:      cfg.Mode,
+		ServiceName:         cfg.ServiceName,
+		Exporter:            exporter,
+		Sampler:             samplerDescription(),
+		Tracers:             map[string]TracerStats{},
+		TracerNames:         []string{},
+		EnrichmentFallbacks: state.fallbacks.Load(),
+		StartedAt:           state.startedAt,
+	}
+
+	if limiter != nil {
+		d.AttributeLimits = limiter.Stats()
+	}
+
+	if counter != nil {
+		d.Tracers = counter.snapshot()
+		for name := range d.Tracers {
+			d.TracerNames = append(d.TracerNames, name)
+		}
+		sort.Strings(d.TracerNames)
+	}
+
+	return d
+}
+
+// DiagnosticsHandler serves the current diagnostics as JSON
+func DiagnosticsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CurrentDiagnostics(r.Context()))
+	})
+}
+
+// samplerDescription describes the sampler the SDK reads from the environment
+func samplerDescription() string {
+	sampler := env.String("OTEL_TRACES_SAMPLER", "parentbased_always_on")
+	if arg := env.String("OTEL_TRACES_SAMPLER_ARG", ""); arg != "" {
+		return sampler + "(" + arg + ")"
+	}
+	return sampler
+}
+
+// spanCounter is a SpanProcessor counting started and ended spans per tracer
+type spanCounter struct {
+	mu      sync.Mutex
+	tracers map[string]*TracerStats
+}
+
+// newSpanCounter creates an empty span counter
+func newSpanCounter() *spanCounter {
+	return &spanCounter{tracers: map[string]*TracerStats{}}
+}
+
+// OnStart counts a started span
+func (c *spanCounter) OnStart(_ context.Context, s sdktrace.ReadWriteSpan) {
+	c.mu.Lock()
+	c.stats(s.InstrumentationScope().Name).Started++
+	c.mu.Unlock()
+}
+
+// OnEnd counts an ended span
+func (c *spanCounter) OnEnd(s sdktrace.ReadOnlySpan) {
+	c.mu.Lock()
+	c.stats(s.InstrumentationScope().Name).Ended++
+	c.mu.Unlock()
+}
+
+// Shutdown does nothing
+func (c *spanCounter) Shutdown(context.Context) error { return nil }
+
+// ForceFlush does nothing
+func (c *spanCounter) ForceFlush(context.Context) error { return nil }
+
+// stats returns the entry for a tracer, creating it if needed; c.mu must be held
+func (c *spanCounter) stats(name string) *TracerStats {
+	s, ok := c.tracers[name]
+	if !ok {
+		s = &TracerStats{}
+		c.tracers[name] = s
+	}
+	return s
+}
+
+// snapshot copies the current counts
+func (c *spanCounter) snapshot() map[string]TracerStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make(map[string]TracerStats, len(c.tracers))
+	for name, s := range c.tracers {
+		out[name] = *s
+	}
+	return out
+}
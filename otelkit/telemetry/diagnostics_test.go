@@ -0,0 +1,35 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAutoSDKDetectedIgnoresParentSpanContext(t *testing.T) {
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), parent)
+
+	if AutoSDKDetected(ctx) {
+		t.Error("AutoSDKDetected reported the no-op tracer as the Auto SDK")
+	}
+}
+
+func TestCurrentDiagnosticsUsesCachedMode(t *testing.T) {
+	t.Cleanup(func() { setDetected("") })
+
+	setDetected(TracingAutoSDK)
+	if got := CurrentDiagnostics(context.Background()).Mode; got != TracingAutoSDK {
+		t.Errorf("Mode = %s, want cached %s", got, TracingAutoSDK)
+	}
+
+	setDetected("")
+	if got := CurrentDiagnostics(context.Background()).Mode; got != TracingNoop {
+		t.Errorf("Mode = %s, want detected %s", got, TracingNoop)
+	}
+}
@@ -0,0 +1,91 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// newMeterProvider creates the SDK MeterProvider exporting through the
+// exporter selected by cfg.Exporter. Its periodic reader collects the
+// observable instruments, such as the database pool metrics, every
+// cfg.MetricsInterval.
+func newMeterProvider(ctx context.Context, cfg Config, res *resource.Resource) (*sdkmetric.MeterProvider, error) {
+	exporter, err := newMetricExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []sdkmetric.PeriodicReaderOption
+	if cfg.MetricsInterval > 0 {
+		opts = append(opts, sdkmetric.WithInterval(cfg.MetricsInterval))
+	}
+
+	return sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, opts...)),
+	), nil
+}
+
+// newMetricExporter creates the metric exporter selected by cfg.Exporter
+func newMetricExporter(ctx context.Context, cfg Config) (sdkmetric.Exporter, error) {
+	switch cfg.Exporter {
+	case ExporterStdout:
+		return stdoutmetric.New(stdoutmetric.WithPrettyPrint())
+	case ExporterFile:
+		f, err := os.OpenFile(cfg.MetricsFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("error opening metrics file: %w", err)
+		}
+		exporter, err := stdoutmetric.New(stdoutmetric.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &fileMetricExporter{Exporter: exporter, file: f}, nil
+	case ExporterOTLPHTTP:
+		var opts []otlpmetrichttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	case ExporterOTLPGRPC:
+		var opts []otlpmetricgrpc.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported telemetry exporter %q", cfg.Exporter)
+	}
+}
+
+// fileMetricExporter is a metric exporter writing to a file it owns
+type fileMetricExporter struct {
+	sdkmetric.Exporter
+	file *os.File
+}
+
+// Shutdown shuts the exporter down, then syncs and closes the file
+func (e *fileMetricExporter) Shutdown(ctx context.Context) error {
+	err := e.Exporter.Shutdown(ctx)
+	if syncErr := e.file.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := e.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
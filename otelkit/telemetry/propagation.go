@@ -0,0 +1,44 @@
+package telemetry
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// DefaultPropagators injects W3C TraceContext and Baggage plus the single
+// B3 header understood by Zipkin-instrumented services
+const DefaultPropagators = "tracecontext,baggage,b3"
+
+// NewPropagator builds the composite propagator named by a comma separated
+// list, as in OTEL_PROPAGATORS: tracecontext, baggage, b3 (single header),
+// b3multi (X-B3-* headers), jaeger (uber-trace-id) or none. Extraction
+// accepts both B3 encodings whichever is configured.
+func NewPropagator(spec string) (propagation.TextMapPropagator, error) {
+	var propagators []propagation.TextMapPropagator
+
+	for _, name := range strings.Split(spec, ",") {
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "":
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "b3":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "jaeger":
+			propagators = append(propagators, jaeger.Jaeger{})
+		case "none":
+			return propagation.NewCompositeTextMapPropagator(), nil
+		default:
+			return nil, fmt.Errorf("unsupported propagator %q", name)
+		}
+	}
+
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
@@ -0,0 +1,49 @@
+package telemetry
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewPropagator(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	tests := []struct {
+		spec    string
+		headers []string
+	}{
+		{DefaultPropagators, []string{"b3", "traceparent"}},
+		{"tracecontext, b3multi", []string{"traceparent", "x-b3-sampled", "x-b3-spanid", "x-b3-traceid"}},
+		{"jaeger", []string{"uber-trace-id"}},
+		{"none", nil},
+	}
+
+	for _, tt := range tests {
+		p, err := NewPropagator(tt.spec)
+		if err != nil {
+			t.Fatalf("NewPropagator(%q): %v", tt.spec, err)
+		}
+
+		carrier := propagation.MapCarrier{}
+		p.Inject(ctx, carrier)
+		keys := carrier.Keys()
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, tt.headers) && len(keys)+len(tt.headers) > 0 {
+			t.Errorf("NewPropagator(%q) injected %v, want %v", tt.spec, keys, tt.headers)
+		}
+	}
+
+	if _, err := NewPropagator("xray"); err == nil {
+		t.Error("NewPropagator accepted an unsupported propagator")
+	}
+}
@@ -0,0 +1,155 @@
+package telemetry
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// routeKey is the context key of the route holder set by ServerMiddleware
+type routeKey struct{}
+
+// route is the route template of a request, set by the handler that
+// matched it
+type route struct {
+	mu       sync.Mutex
+	template string
+}
+
+// SetRoute records the route template, e.g. /users/{username}, that
+// matched the request; ServerMiddleware names the server span after it
+func SetRoute(ctx context.Context, template string) {
+	if r, ok := ctx.Value(routeKey{}).(*route); ok {
+		r.mu.Lock()
+		r.template = template
+		r.mu.Unlock()
+	}
+}
+
+// ServerMiddleware continues the caller's trace: it extracts the trace
+// context and baggage from the request headers with the global propagator
+// (see NewPropagator) and starts a server span named after the route
+// template. Handlers report the template with SetRoute; otherwise the
+// ServeMux pattern is used.
+//
+// When the Auto SDK is detected, eBPF instrumentation already extracts the
+// context and owns the server spans, so the middleware does nothing.
+func ServerMiddleware(next http.Handler) http.Handler {
+	tracer := otel.Tracer("telemetry")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if detectedMode(r.Context()) == TracingAutoSDK {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		rt := &route{}
+		ctx = context.WithValue(ctx, routeKey{}, rt)
+
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(serverAttributes(r)...),
+		)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		req := r.WithContext(ctx)
+		next.ServeHTTP(rec, req)
+
+		// The ServeMux sets the matched pattern on the request it serves
+		rt.mu.Lock()
+		template := rt.template
+		rt.mu.Unlock()
+		if template == "" {
+			template = patternRoute(req.Pattern)
+		}
+		if template != "" {
+			span.SetName(r.Method + " " + template)
+			span.SetAttributes(semconv.HTTPRoute(template))
+		}
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.status))
+		// Per the HTTP semantic conventions only 5xx responses fail a server span
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+			span.SetAttributes(semconv.ErrorTypeKey.String(strconv.Itoa(rec.status)))
+		}
+	})
+}
+
+// patternRoute returns the path of a ServeMux pattern, which may start with
+// a method and a host, e.g. "GET example.com/users/{username}"
+func patternRoute(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		pattern = strings.TrimSpace(path)
+	}
+	if i := strings.Index(pattern, "/"); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
+
+// serverAttributes returns the semantic convention attributes known when
+// the request arrives. The query string is left out, it can carry personal
+// data the redaction policies do not know about.
+func serverAttributes(r *http.Request) []attribute.KeyValue {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLScheme(scheme),
+		semconv.URLPath(r.URL.Path),
+		semconv.NetworkProtocolVersion(strconv.Itoa(r.ProtoMajor) + "." + strconv.Itoa(r.ProtoMinor)),
+	}
+	if host, port, err := net.SplitHostPort(r.Host); err == nil {
+		attrs = append(attrs, semconv.ServerAddress(host))
+		if p, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, semconv.ServerPort(p))
+		}
+	} else if r.Host != "" {
+		attrs = append(attrs, semconv.ServerAddress(r.Host))
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		attrs = append(attrs, semconv.ClientAddress(host))
+	}
+	if ua := r.UserAgent(); ua != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(ua))
+	}
+	return attrs
+}
+
+// statusRecorder captures the status code written by the handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader records the status code and writes it
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
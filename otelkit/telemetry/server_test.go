@@ -0,0 +1,37 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerMiddlewareSkipsAutoSDK(t *testing.T) {
+	t.Cleanup(func() { setDetected("") })
+	setDetected(TracingAutoSDK)
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	var got *http.Request
+	ServerMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	if got != req {
+		t.Error("middleware replaced the request although the Auto SDK owns server spans")
+	}
+}
+
+func TestPatternRoute(t *testing.T) {
+	tests := map[string]string{
+		"":                                 "",
+		"/users/":                          "/users/",
+		"GET /api/call":                    "/api/call",
+		"GET example.com/users/{username}": "/users/{username}",
+		"example.com/users/{username}/{$}": "/users/{username}/{$}",
+	}
+	for pattern, want := range tests {
+		if got := patternRoute(pattern); got != want {
+			t.Errorf("patternRoute(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+// Package telemetry bootstraps an OpenTelemetry SDK TracerProvider and
+// MeterProvider for running the services without eBPF auto-instrumentation.
+//
+// The bootstrap is opt-in: by default (TELEMETRY_MODE=auto) the global
+// TracerProvider is left untouched so the Go Auto SDK can own it. Setting a
+// global TracerProvider while the Auto SDK is attached prevents manual spans
+// from correlating with eBPF spans (see otelapi/NotPossible.md), so Setup
+// also refuses to install one when the Auto SDK is detected.
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"otelkit/attrlimit"
+	"otelkit/baggageattr"
+	"otelkit/env"
+	"otelkit/redact"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Telemetry modes
+const (
+	// ModeAuto leaves the global TracerProvider to eBPF auto-instrumentation
+	ModeAuto = "auto"
+	// ModeSDK installs an in-process SDK TracerProvider
+	ModeSDK = "sdk"
+)
+
+// Exporters
+const (
+	ExporterStdout   = "stdout"
+	ExporterFile     = "file"
+	ExporterOTLPHTTP = "otlp-http"
+	ExporterOTLPGRPC = "otlp-grpc"
+)
+
+// Config holds the telemetry bootstrap settings
+type Config struct {
+	// Mode is "auto" (default) or "sdk"
+	Mode string
+	// ServiceName is reported as service.name
+	ServiceName string
+	// ServiceVersion is reported as service.version
+	ServiceVersion string
+	// Environment is reported as deployment.environment
+	Environment string
+	// Exporter selects the span exporter used in SDK mode
+	Exporter string
+	// FilePath is the JSON-lines output file for the "file" exporter
+	FilePath string
+	// MetricsFilePath is the JSON-lines metrics output file for the "file"
+	// exporter
+	MetricsFilePath string
+	// MetricsInterval is how often metrics are collected and exported;
+	// zero uses OTEL_METRIC_EXPORT_INTERVAL or the SDK default of 60s
+	MetricsInterval time.Duration
+	// Endpoint overrides the OTLP endpoint (host:port), otherwise OTEL_EXPORTER_OTLP_* env vars apply
+	Endpoint string
+	// Insecure disables TLS for OTLP exporters
+	Insecure bool
+	// Redact enables PII redaction of span attributes
+	Redact bool
+	// RedactPolicies overrides the default policies, see redact.ParsePolicies
+	RedactPolicies string
+	// RedactSalt is prepended to values before hashing; a random salt is
+	// used when empty, so hashes only correlate within one process
+	RedactSalt string
+	// Limits bounds span attribute counts, value lengths and cardinality
+	Limits attrlimit.Limits
+	// BaggageAttributes lists the baggage members promoted to span
+	// attributes, see baggageattr.ParseAllowlist
+	BaggageAttributes string
+	// Propagators lists the propagators injecting trace context into
+	// outbound requests, see NewPropagator
+	Propagators string
+}
+
+// ConfigFromEnv builds a Config from environment variables, using
+// serviceName when OTEL_SERVICE_NAME is not set
+func ConfigFromEnv(serviceName string) Config {
+	return Config{
+		Mode:           strings.ToLower(env.String("TELEMETRY_MODE", ModeAuto)),
+		ServiceName:    env.String("OTEL_SERVICE_NAME", serviceName),
+		ServiceVersion: env.String("SERVICE_VERSION", "1.0.0"),
+		Environment:    env.String("DEPLOYMENT_ENVIRONMENT", "development"),
+		Exporter:       strings.ToLower(env.String("TELEMETRY_EXPORTER", ExporterStdout)),
+		FilePath:       env.String("TELEMETRY_FILE", "traces.jsonl"),
+		Endpoint:       env.String("TELEMETRY_OTLP_ENDPOINT", ""),
+		Insecure:       env.Bool("TELEMETRY_OTLP_INSECURE", true),
+		Redact:         env.Bool("REDACT", true),
+		RedactPolicies: env.String("REDACT_POLICIES", ""),
+		RedactSalt:     env.String("REDACT_SALT", ""),
+		Limits:         limitsFromEnv(),
+
+		BaggageAttributes: env.String("BAGGAGE_ATTRIBUTES", baggageattr.DefaultAllowlist),
+		Propagators:       env.String("OTEL_PROPAGATORS", DefaultPropagators),
+		MetricsFilePath:   env.String("TELEMETRY_METRICS_FILE", "metrics.jsonl"),
+		MetricsInterval:   env.Duration("TELEMETRY_METRICS_INTERVAL", 0),
+	}
+}
+
+// limitsFromEnv reads the attribute limits, using the defaults for unset
+// or invalid values
+func limitsFromEnv() attrlimit.Limits {
+	limits := attrlimit.DefaultLimits()
+	limits.MaxAttributes = env.Int("LIMITS_MAX_ATTRIBUTES", limits.MaxAttributes)
+	limits.MaxValueLength = env.Int("LIMITS_MAX_VALUE_LENGTH", limits.MaxValueLength)
+	limits.MaxSliceLength = env.Int("LIMITS_MAX_SLICE_LENGTH", limits.MaxSliceLength)
+	limits.MaxDistinctValues = env.Int("LIMITS_MAX_DISTINCT_VALUES", limits.MaxDistinctValues)
+	limits.CardinalityAction = attrlimit.Action(strings.ToLower(env.String("LIMITS_CARDINALITY_ACTION", string(limits.CardinalityAction))))
+	return limits
+}
+
+// Setup installs a global SDK TracerProvider and MeterProvider when
+// cfg.Mode is "sdk" and the Auto SDK is not detected. The returned function
+// flushes and shuts the providers down; it is a no-op when none was
+// installed.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+
+	state.mu.Lock()
+	state.cfg = cfg
+	state.mu.Unlock()
+
+	redactor, err := newRedactor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := attrlimit.ParseAction(string(cfg.Limits.CardinalityAction)); err != nil {
+		return nil, err
+	}
+	limiter := attrlimit.New(cfg.Limits)
+
+	allow, err := baggageattr.ParseAllowlist(cfg.BaggageAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	// The propagator only injects and extracts headers, so unlike the
+	// TracerProvider it is safe to install alongside the Auto SDK
+	propagator, err := NewPropagator(cfg.Propagators)
+	if err != nil {
+		return nil, err
+	}
+	otel.SetTextMapPropagator(propagator)
+
+	state.mu.Lock()
+	state.limiter = limiter
+	state.baggage = allow
+	state.mu.Unlock()
+
+	// Spans that are not exported by an in-process SDK are redacted and
+	// limited before SetAttributes through WrapSpan, and get the promoted
+	// baggage through PromoteBaggage
+	hooks := &spanHooks{redactor: redactor, limiter: limiter, baggage: allow}
+
+	switch cfg.Mode {
+	case ModeAuto, "":
+		detected := Detect(ctx)
+		setDetected(detected)
+		setSpanHooks(hooks)
+		log.Printf("Telemetry mode auto: relying on zero-code instrumentation (detected %s)", detected)
+		return noop, nil
+	case ModeSDK:
+	default:
+		return nil, fmt.Errorf("unsupported telemetry mode %q", cfg.Mode)
+	}
+
+	if AutoSDKDetected(ctx) {
+		setDetected(TracingAutoSDK)
+		setSpanHooks(hooks)
+		log.Println("WARNING: Auto SDK detected, not installing an SDK TracerProvider")
+		return noop, nil
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(cfg.ServiceVersion),
+		semconv.DeploymentEnvironment(cfg.Environment),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("error creating resource: %w", err)
+	}
+
+	counter := newSpanCounter()
+
+	var export sdktrace.SpanProcessor
+	if cfg.Exporter == ExporterStdout {
+		export = sdktrace.NewSimpleSpanProcessor(exporter)
+	} else {
+		export = sdktrace.NewBatchSpanProcessor(exporter)
+	}
+	// Redaction runs first, so the limits apply to the redacted values
+	export = attrlimit.NewProcessor(export, limiter)
+	if redactor != nil {
+		export = redact.NewProcessor(export, redactor)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(baggageattr.NewProcessor(allow)),
+		sdktrace.WithSpanProcessor(counter),
+		sdktrace.WithSpanProcessor(export),
+	)
+	otel.SetTracerProvider(tp)
+
+	mp, err := newMeterProvider(ctx, cfg, res)
+	if err != nil {
+		tp.Shutdown(ctx)
+		return nil, err
+	}
+	otel.SetMeterProvider(mp)
+
+	state.mu.Lock()
+	state.exporter = cfg.Exporter
+	state.counter = counter
+	state.mu.Unlock()
+	setDetected(TracingSDK)
+
+	log.Printf("Telemetry mode sdk: exporting spans and metrics for %s via %s", cfg.ServiceName, cfg.Exporter)
+
+	return func(ctx context.Context) error {
+		return errors.Join(tp.Shutdown(ctx), mp.Shutdown(ctx))
+	}, nil
+}
+
+// newRedactor builds the attribute redactor configured by cfg, or nil when
+// redaction is disabled
+func newRedactor(cfg Config) (*redact.Redactor, error) {
+	if !cfg.Redact {
+		return nil, nil
+	}
+
+	overrides, err := redact.ParsePolicies(cfg.RedactPolicies)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := cfg.RedactSalt
+	if salt == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("error generating redaction salt: %w", err)
+		}
+		salt = hex.EncodeToString(b)
+		log.Println("WARNING: REDACT_SALT is not set, hashed attributes only correlate within this process")
+	}
+
+	return redact.New(redact.DefaultPolicies().Merge(overrides), salt), nil
+}
+
+// AutoSDKDetected reports whether eBPF auto-instrumentation has taken over
+// the global tracer. The Auto SDK populates the span context of spans
+// started from the global tracer without making them recording, so a valid
+// but non-recording probe span identifies it. Detection only succeeds once
+// the eBPF agent has attached to the process.
+//
+// The probe is started without a parent: a no-op tracer propagates a valid
+// parent span context from ctx, which would look like the Auto SDK.
+func AutoSDKDetected(ctx context.Context) bool {
+	ctx = trace.ContextWithSpanContext(ctx, trace.SpanContext{})
+	_, span := otel.Tracer("telemetry").Start(ctx, "telemetry.probe")
+	defer span.End()
+
+	return !span.IsRecording() && span.SpanContext().IsValid()
+}
+
+// newExporter creates the span exporter selected by cfg.Exporter
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		f, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("error opening trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &fileExporter{SpanExporter: exporter, file: f}, nil
+	case ExporterOTLPHTTP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case ExporterOTLPGRPC:
+		var opts []otlptracegrpc.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported telemetry exporter %q", cfg.Exporter)
+	}
+}
+
+// fileExporter is a span exporter writing to a file it owns
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+// Shutdown shuts the exporter down, then syncs and closes the file
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if syncErr := e.file.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := e.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
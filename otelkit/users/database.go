@@ -0,0 +1,274 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"regexp"
+	"time"
+
+	"otelkit/apmattr"
+	"otelkit/env"
+	"otelkit/sqltrace"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Database systems, reported as db.system
+const (
+	SystemPostgres = "postgresql"
+	SystemSQLite   = "sqlite"
+)
+
+// Database holds the database connection. Its connections are instrumented
+// with sqltrace, so every statement gets a client span, and the pool
+// statistics are reported as metrics.
+type Database struct {
+	DB *sql.DB
+	// System is the database system, SystemPostgres or SystemSQLite
+	System string
+
+	poolMetrics metric.Registration
+}
+
+// PoolConfig holds the connection pool settings
+type PoolConfig struct {
+	// MaxOpenConns bounds the open connections; zero is unlimited
+	MaxOpenConns int
+	// MaxIdleConns bounds the idle connections kept for reuse
+	MaxIdleConns int
+	// ConnMaxLifetime closes connections older than it; zero keeps them
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime closes connections idle for longer than it; zero
+	// keeps them
+	ConnMaxIdleTime time.Duration
+}
+
+// DefaultPoolConfig returns the pool settings used when none are configured
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    25,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+	}
+}
+
+// PoolConfigFromEnv reads the pool settings from DB_MAX_OPEN_CONNS,
+// DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME and DB_CONN_MAX_IDLE_TIME, using
+// the defaults for unset or invalid values
+func PoolConfigFromEnv() PoolConfig {
+	cfg := DefaultPoolConfig()
+	cfg.MaxOpenConns = env.Int("DB_MAX_OPEN_CONNS", cfg.MaxOpenConns)
+	cfg.MaxIdleConns = env.Int("DB_MAX_IDLE_CONNS", cfg.MaxIdleConns)
+	cfg.ConnMaxLifetime = env.Duration("DB_CONN_MAX_LIFETIME", cfg.ConnMaxLifetime)
+	cfg.ConnMaxIdleTime = env.Duration("DB_CONN_MAX_IDLE_TIME", cfg.ConnMaxIdleTime)
+	return cfg
+}
+
+// ConnectConfig holds the settings of the connection retry at startup
+type ConnectConfig struct {
+	// Timeout bounds all attempts together; zero retries until the context
+	// is done
+	Timeout time.Duration
+	// AttemptTimeout bounds a single attempt; zero leaves it to the driver
+	AttemptTimeout time.Duration
+	// InitialBackoff is the delay after the first failed attempt. It doubles
+	// after every further failure up to MaxBackoff, and each delay is
+	// randomly shortened by up to half so that restarted replicas do not
+	// retry in lockstep.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultConnectConfig returns the retry settings used when none are configured
+func DefaultConnectConfig() ConnectConfig {
+	return ConnectConfig{
+		Timeout:        2 * time.Minute,
+		AttemptTimeout: 5 * time.Second,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     15 * time.Second,
+	}
+}
+
+// ConnectConfigFromEnv reads the retry settings from DB_CONNECT_TIMEOUT,
+// DB_CONNECT_ATTEMPT_TIMEOUT, DB_CONNECT_INITIAL_BACKOFF and
+// DB_CONNECT_MAX_BACKOFF, using the defaults for unset or invalid values
+func ConnectConfigFromEnv() ConnectConfig {
+	cfg := DefaultConnectConfig()
+	cfg.Timeout = env.Duration("DB_CONNECT_TIMEOUT", cfg.Timeout)
+	cfg.AttemptTimeout = env.Duration("DB_CONNECT_ATTEMPT_TIMEOUT", cfg.AttemptTimeout)
+	cfg.InitialBackoff = env.Duration("DB_CONNECT_INITIAL_BACKOFF", cfg.InitialBackoff)
+	cfg.MaxBackoff = env.Duration("DB_CONNECT_MAX_BACKOFF", cfg.MaxBackoff)
+	return cfg
+}
+
+// NewDatabase opens a PostgreSQL database. It does not connect; call
+// Connect to wait until the server is reachable.
+func NewDatabase(host, port, user, password, dbname string) (*Database, error) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		host, port, user, password, dbname)
+
+	return open("postgres", connStr, SystemPostgres)
+}
+
+// NewSQLiteDatabase opens the SQLite database file at path, creating it on
+// the first connection
+func NewSQLiteDatabase(path string) (*Database, error) {
+	// Wait for locks instead of failing with SQLITE_BUSY, and let readers
+	// run alongside the writer
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on", path)
+
+	return open("sqlite3", dsn, SystemSQLite)
+}
+
+// open opens an instrumented database and registers its pool metrics
+func open(driverName, dsn, system string) (*Database, error) {
+	cfg := sqltrace.Config{TracerName: "users", System: system}
+
+	db, err := sqltrace.Open(driverName, dsn, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+
+	reg, err := sqltrace.RegisterPoolMetrics(db, "users", cfg)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error registering pool metrics: %w", err)
+	}
+
+	return &Database{DB: db, System: system, poolMetrics: reg}, nil
+}
+
+// Connect waits until the database is reachable, retrying with exponential
+// backoff and jitter. Every attempt is traced as a db.connect span. It gives
+// up when cfg.Timeout has passed or ctx is done and returns the last
+// connection error.
+func (d *Database) Connect(ctx context.Context, cfg ConnectConfig) error {
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	backoff := cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		spanCtx, span := otel.Tracer("users").Start(ctx, "db.connect",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(d.System),
+				apmattr.DBConnectAttempt.Int(attempt),
+			),
+		)
+
+		err := d.ping(spanCtx, cfg.AttemptTimeout)
+		if err == nil {
+			span.End()
+			log.Printf("Successfully connected to database (attempt %d)", attempt)
+			return nil
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		// Give up when the next attempt would start after the deadline
+		wait := jitter(backoff)
+		if deadline, ok := ctx.Deadline(); ctx.Err() != nil || ok && time.Until(deadline) < wait {
+			span.End()
+			return fmt.Errorf("error connecting to database after %d attempts: %w", attempt, err)
+		}
+		span.SetAttributes(apmattr.DBConnectBackoffMS.Float64(float64(wait) / float64(time.Millisecond)))
+		span.End()
+
+		log.Printf("Database not reachable (attempt %d), retrying in %s: %v", attempt, wait.Round(time.Millisecond), err)
+		if sleep(ctx, wait) != nil {
+			return fmt.Errorf("error connecting to database after %d attempts: %w", attempt, err)
+		}
+		backoff = min(2*backoff, cfg.MaxBackoff)
+	}
+}
+
+// ping checks that a connection can be opened, bounded by timeout when it
+// is positive
+func (d *Database) ping(ctx context.Context, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return d.DB.PingContext(ctx)
+}
+
+// jitter returns a random delay between half of d and d
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + rand.N(d-d/2)
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ConfigurePool applies the pool settings
+func (d *Database) ConfigurePool(cfg PoolConfig) {
+	d.DB.SetMaxOpenConns(cfg.MaxOpenConns)
+	d.DB.SetMaxIdleConns(cfg.MaxIdleConns)
+	d.DB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	d.DB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// conn takes a connection from the pool for an operation and records on
+// span how long the operation waited for it. Closing the connection
+// returns it to the pool.
+func (d *Database) conn(ctx context.Context, span trace.Span) (*sql.Conn, error) {
+	start := time.Now()
+	conn, err := d.DB.Conn(ctx)
+	span.SetAttributes(apmattr.DBConnectionWaitMS.Float64(float64(time.Since(start)) / float64(time.Millisecond)))
+	return conn, err
+}
+
+// placeholderPattern matches the PostgreSQL $N query placeholders
+var placeholderPattern = regexp.MustCompile(`\$(\d+)`)
+
+// rebind rewrites the $N placeholders of a PostgreSQL query for the
+// database system; SQLite uses ?N
+func (d *Database) rebind(query string) string {
+	if d.System != SystemSQLite {
+		return query
+	}
+	return placeholderPattern.ReplaceAllString(query, "?$1")
+}
+
+// caseInsensitiveLike returns the case-insensitive LIKE condition on
+// column for the database system, with backslash escapes
+func (d *Database) caseInsensitiveLike(column string) string {
+	if d.System == SystemSQLite {
+		// SQLite's LIKE is case-insensitive but has no default escape character
+		return column + ` LIKE $%d ESCAPE '\'`
+	}
+	return column + " ILIKE $%d"
+}
+
+// Close stops reporting the pool metrics and closes the database
+func (d *Database) Close() error {
+	d.poolMetrics.Unregister()
+	return d.DB.Close()
+}
@@ -0,0 +1,178 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"otelkit/instrument"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Errors returned by UserRepository. Repository errors wrap one of these
+// together with the underlying driver error, so callers classify them
+// with errors.Is.
+var (
+	// ErrUserNotFound means no user matched the username
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUsernameConflict means the username is already taken
+	ErrUsernameConflict = errors.New("username already exists")
+	// ErrEmailConflict means the email is already used by another user
+	ErrEmailConflict = errors.New("email already exists")
+	// ErrValidation means the database rejected the user data
+	ErrValidation = errors.New("invalid user data")
+	// ErrUnavailable means the database could not be reached
+	ErrUnavailable = errors.New("database unavailable")
+	// ErrCanceled means the request was canceled, usually because the
+	// client disconnected; it is not a server error
+	ErrCanceled = errors.New("request canceled")
+
+	// ErrInvalidRequest means the request could not be parsed or is missing fields
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrMethodNotAllowed means the route does not support the request method
+	ErrMethodNotAllowed = errors.New("method not allowed")
+)
+
+// statusClientClosedRequest is the non-standard status logged for requests
+// the client gave up on
+const statusClientClosedRequest = 499
+
+// Unique constraints on go_user_tbl in PostgreSQL
+const (
+	usernameConstraint = "go_user_tbl_pkey"
+	emailConstraint    = "go_user_tbl_email_key"
+)
+
+// classifyError wraps a database error with the matching sentinel error.
+// Errors that match no sentinel are returned unchanged.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrUserNotFound, err)
+	}
+
+	// The driver returns the context error when the request times out or
+	// the client disconnects
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("%w: %w", ErrCanceled, err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code.Name() == "unique_violation":
+			if pqErr.Constraint == emailConstraint || strings.Contains(pqErr.Message, "email") {
+				return fmt.Errorf("%w: %w", ErrEmailConflict, err)
+			}
+			if pqErr.Constraint == usernameConstraint || strings.Contains(pqErr.Message, "pkey") {
+				return fmt.Errorf("%w: %w", ErrUsernameConflict, err)
+			}
+			// a unique constraint this repository does not know about
+			return fmt.Errorf("%w: %w", ErrValidation, err)
+		case pqErr.Code.Class() == "22", pqErr.Code.Class() == "23":
+			// data exception and the remaining integrity constraint violations
+			return fmt.Errorf("%w: %w", ErrValidation, err)
+		case pqErr.Code.Class() == "08", pqErr.Code.Class() == "53", pqErr.Code.Class() == "57":
+			// connection exception, insufficient resources, operator intervention
+			return fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch {
+		case sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique,
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+			// e.g. "UNIQUE constraint failed: go_user_tbl.email"
+			if strings.Contains(sqliteErr.Error(), "go_user_tbl.email") {
+				return fmt.Errorf("%w: %w", ErrEmailConflict, err)
+			}
+			if strings.Contains(sqliteErr.Error(), "go_user_tbl.username") {
+				return fmt.Errorf("%w: %w", ErrUsernameConflict, err)
+			}
+			return fmt.Errorf("%w: %w", ErrValidation, err)
+		case sqliteErr.Code == sqlite3.ErrConstraint, sqliteErr.Code == sqlite3.ErrMismatch,
+			sqliteErr.Code == sqlite3.ErrTooBig:
+			return fmt.Errorf("%w: %w", ErrValidation, err)
+		case sqliteErr.Code == sqlite3.ErrBusy, sqliteErr.Code == sqlite3.ErrLocked,
+			sqliteErr.Code == sqlite3.ErrCantOpen, sqliteErr.Code == sqlite3.ErrIoErr,
+			sqliteErr.Code == sqlite3.ErrFull, sqliteErr.Code == sqlite3.ErrReadonly:
+			return fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	return err
+}
+
+// ErrorType returns the error.type attribute value for an error
+func ErrorType(err error) string {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		return "not_found"
+	case errors.Is(err, ErrUsernameConflict):
+		return "username_conflict"
+	case errors.Is(err, ErrEmailConflict):
+		return "email_conflict"
+	case errors.Is(err, ErrValidation):
+		return "validation"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrCanceled):
+		return "canceled"
+	case errors.Is(err, ErrInvalidRequest):
+		return "invalid_request"
+	case errors.Is(err, ErrMethodNotAllowed):
+		return "method_not_allowed"
+	default:
+		return "internal"
+	}
+}
+
+// StatusCode maps an error to the HTTP status code returned to the client
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUsernameConflict), errors.Is(err, ErrEmailConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrCanceled):
+		return statusClientClosedRequest
+	case errors.Is(err, ErrInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrMethodNotAllowed):
+		return http.StatusMethodNotAllowed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// recordError records err on the span, classified by its error type and
+// the HTTP status code it maps to, and returns it marked as recorded
+func recordError(span trace.Span, err error) error {
+	return instrument.RecordError(span, err, ErrorType(err), StatusCode(err))
+}
@@ -0,0 +1,61 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		want   error
+		status int
+	}{
+		{"no rows", sql.ErrNoRows, ErrUserNotFound, http.StatusNotFound},
+		{"email unique", &pq.Error{Code: "23505", Constraint: emailConstraint}, ErrEmailConflict, http.StatusConflict},
+		{"username unique", &pq.Error{Code: "23505", Constraint: usernameConstraint}, ErrUsernameConflict, http.StatusConflict},
+		{"other unique", &pq.Error{Code: "23505", Constraint: "go_user_tbl_other_key"}, ErrValidation, http.StatusUnprocessableEntity},
+		{"not null", &pq.Error{Code: "23502"}, ErrValidation, http.StatusUnprocessableEntity},
+		{"data exception", &pq.Error{Code: "22001"}, ErrValidation, http.StatusUnprocessableEntity},
+		{"connection", &pq.Error{Code: "08006"}, ErrUnavailable, http.StatusServiceUnavailable},
+		{"deadline", context.DeadlineExceeded, ErrUnavailable, http.StatusServiceUnavailable},
+		{"wrapped deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), ErrUnavailable, http.StatusServiceUnavailable},
+		{"canceled", context.Canceled, ErrCanceled, statusClientClosedRequest},
+		{"connection done", sql.ErrConnDone, ErrUnavailable, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyError(tt.err)
+			if !errors.Is(got, tt.want) {
+				t.Errorf("classifyError() = %v, want %v", got, tt.want)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Errorf("classifyError() = %v, lost the underlying error", got)
+			}
+			if status := StatusCode(got); status != tt.status {
+				t.Errorf("StatusCode() = %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestClassifyErrorUnknown(t *testing.T) {
+	err := errors.New("boom")
+	if got := classifyError(err); got != err {
+		t.Errorf("classifyError() = %v, want the error unchanged", got)
+	}
+	if status := StatusCode(err); status != http.StatusInternalServerError {
+		t.Errorf("StatusCode() = %d, want 500", status)
+	}
+	if typ := ErrorType(err); typ != "internal" {
+		t.Errorf("ErrorType() = %q, want internal", typ)
+	}
+}
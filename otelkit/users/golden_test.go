@@ -0,0 +1,50 @@
+package users
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"otelkit/instrument"
+	"otelkit/spantest"
+)
+
+// TestTraceShapes locks down the trace tree of every endpoint; run
+// go test ./users -update to regenerate testdata/traces after an intended change
+func TestTraceShapes(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   instrument.Mode
+		method string
+		target string
+		body   string
+	}{
+		{"create_user", instrument.ModeChildSpan, http.MethodPost, "/users/", `{"username":"newuser","name":"New User","email":"new@example.com","age":40}`},
+		{"create_user_conflict", instrument.ModeChildSpan, http.MethodPost, "/users/", `{"username":"johndoe","name":"John","email":"other@example.com","age":30}`},
+		{"get_user", instrument.ModeChildSpan, http.MethodGet, "/users/johndoe", ""},
+		{"get_user_not_found", instrument.ModeChildSpan, http.MethodGet, "/users/nobody", ""},
+		{"get_all_users", instrument.ModeChildSpan, http.MethodGet, "/users/?limit=1&name=doe", ""},
+		{"update_user", instrument.ModeChildSpan, http.MethodPut, "/users/johndoe", `{"name":"John","email":"john@example.org","age":31}`},
+		{"delete_user", instrument.ModeChildSpan, http.MethodDelete, "/users/johndoe", ""},
+		{"method_not_allowed", instrument.ModeChildSpan, http.MethodPatch, "/users/johndoe", ""},
+		{"get_user_enrich", instrument.ModeEnrich, http.MethodGet, "/users/johndoe", ""},
+		{"get_user_both", instrument.ModeBoth, http.MethodGet, "/users/johndoe", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := spantest.New(t)
+
+			store := NewMemoryStore(instrument.New("test", tt.mode))
+			seed := CreateUserRequest{Username: "johndoe", Name: "John Doe", Email: "john@example.com", Age: 30}
+			if _, err := store.CreateUser(context.Background(), seed); err != nil {
+				t.Fatal(err)
+			}
+			rec.Reset()
+
+			serveTraced(t, tt.mode, store, tt.method, tt.target, tt.body)
+			rec.Golden(filepath.Join("testdata", "traces", tt.name+".json"))
+		})
+	}
+}
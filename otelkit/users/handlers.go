@@ -0,0 +1,342 @@
+package users
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"otelkit/apmattr"
+	"otelkit/instrument"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// UserHandler handles HTTP requests for user operations
+type UserHandler struct {
+	repo  UserStore
+	instr *instrument.Instrumenter
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(repo UserStore, instr *instrument.Instrumenter) *UserHandler {
+	return &UserHandler{repo: repo, instr: instr}
+}
+
+// CreateUser handles POST /users
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.instr.Start(r.Context(), "CreateUser")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.HTTPMethod.String(r.Method),
+		apmattr.HTTPURL.String(r.URL.String()),
+		apmattr.Operation.String("create_user"),
+	)
+
+	if r.Method != http.MethodPost {
+		writeError(w, span, ErrMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, span, fmt.Errorf("%w: %w", ErrInvalidRequest, err), "Invalid request body")
+		return
+	}
+
+	span.SetAttributes(
+		apmattr.UserUsername.String(req.Username),
+		apmattr.UserEmail.String(req.Email),
+	)
+
+	if req.Username == "" || req.Name == "" || req.Email == "" || req.Age <= 0 {
+		writeError(w, span, fmt.Errorf("%w: missing required fields", ErrInvalidRequest), "Username, name, email, and age are required")
+		return
+	}
+
+	user, err := h.repo.CreateUser(ctx, req)
+	if err != nil {
+		writeError(w, span, err, "Error creating user")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// GetUser handles GET /users/{username}
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.instr.Start(r.Context(), "GetUser")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.HTTPMethod.String(r.Method),
+		apmattr.HTTPURL.String(r.URL.String()),
+		apmattr.Operation.String("get_user"),
+	)
+
+	if r.Method != http.MethodGet {
+		writeError(w, span, ErrMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	username, err := extractUsernameFromPath(r.URL.Path)
+	if err != nil {
+		writeError(w, span, fmt.Errorf("%w: %w", ErrInvalidRequest, err), "Invalid username")
+		return
+	}
+
+	span.SetAttributes(apmattr.UserUsername.String(username))
+
+	user, err := h.repo.GetUserByUsername(ctx, username)
+	if err != nil {
+		writeError(w, span, err, "Error getting user")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// GetAllUsers handles GET /users
+func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.instr.Start(r.Context(), "GetAllUsers")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.HTTPMethod.String(r.Method),
+		apmattr.HTTPURL.String(r.URL.String()),
+		apmattr.Operation.String("get_all_users"),
+	)
+
+	if r.Method != http.MethodGet {
+		writeError(w, span, ErrMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query, err := parseListUsersQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, span, err, err.Error())
+		return
+	}
+
+	page, err := h.repo.GetAllUsers(ctx, query)
+	if err != nil {
+		writeError(w, span, err, "Error getting users")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// UpdateUser handles PUT /users/{username}
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.instr.Start(r.Context(), "UpdateUser")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.HTTPMethod.String(r.Method),
+		apmattr.HTTPURL.String(r.URL.String()),
+		apmattr.Operation.String("update_user"),
+	)
+
+	if r.Method != http.MethodPut {
+		writeError(w, span, ErrMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	username, err := extractUsernameFromPath(r.URL.Path)
+	if err != nil {
+		writeError(w, span, fmt.Errorf("%w: %w", ErrInvalidRequest, err), "Invalid username")
+		return
+	}
+
+	span.SetAttributes(apmattr.UserUsername.String(username))
+
+	var req UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, span, fmt.Errorf("%w: %w", ErrInvalidRequest, err), "Invalid request body")
+		return
+	}
+
+	if req.Name == "" || req.Email == "" || req.Age <= 0 {
+		writeError(w, span, fmt.Errorf("%w: missing required fields", ErrInvalidRequest), "Name, email, and age are required")
+		return
+	}
+
+	user, err := h.repo.UpdateUser(ctx, username, req)
+	if err != nil {
+		writeError(w, span, err, "Error updating user")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// DeleteUser handles DELETE /users/{username}
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.instr.Start(r.Context(), "DeleteUser")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.HTTPMethod.String(r.Method),
+		apmattr.HTTPURL.String(r.URL.String()),
+		apmattr.Operation.String("delete_user"),
+	)
+
+	if r.Method != http.MethodDelete {
+		writeError(w, span, ErrMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	username, err := extractUsernameFromPath(r.URL.Path)
+	if err != nil {
+		writeError(w, span, fmt.Errorf("%w: %w", ErrInvalidRequest, err), "Invalid username")
+		return
+	}
+
+	span.SetAttributes(apmattr.UserUsername.String(username))
+
+	err = h.repo.DeleteUser(ctx, username)
+	if err != nil {
+		writeError(w, span, err, "Error deleting user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Page sizes for GET /users
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// parseListUsersQuery parses the pagination, sort and filter query parameters
+func parseListUsersQuery(values url.Values) (ListUsersQuery, error) {
+	q := ListUsersQuery{
+		Limit: defaultPageSize,
+		Sort:  "username",
+		Name:  values.Get("name"),
+		Email: values.Get("email"),
+	}
+
+	intParams := []struct {
+		name   string
+		target *int
+	}{
+		{"limit", &q.Limit},
+		{"offset", &q.Offset},
+		{"min_age", &q.MinAge},
+		{"max_age", &q.MaxAge},
+	}
+	for _, p := range intParams {
+		raw := values.Get(p.name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			return q, fmt.Errorf("%w: %s must be a non-negative integer", ErrInvalidRequest, p.name)
+		}
+		*p.target = v
+	}
+
+	if q.Limit < 1 || q.Limit > maxPageSize {
+		return q, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidRequest, maxPageSize)
+	}
+
+	if q.MinAge > 0 && q.MaxAge > 0 && q.MinAge > q.MaxAge {
+		return q, fmt.Errorf("%w: min_age must not exceed max_age", ErrInvalidRequest)
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		q.Desc = strings.HasPrefix(sort, "-")
+		q.Sort = strings.TrimPrefix(sort, "-")
+		if _, ok := sortColumns[q.Sort]; !ok {
+			return q, fmt.Errorf("%w: unsupported sort field %q", ErrInvalidRequest, q.Sort)
+		}
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		if q.Sort != "username" {
+			return q, fmt.Errorf("%w: cursor pagination requires sorting by username", ErrInvalidRequest)
+		}
+		if q.Offset > 0 {
+			return q, fmt.Errorf("%w: cursor and offset cannot be combined", ErrInvalidRequest)
+		}
+		username, err := DecodeCursor(cursor)
+		if err != nil {
+			return q, err
+		}
+		q.Cursor = username
+	}
+
+	return q, nil
+}
+
+// EncodeCursor encodes the last username of a page as an opaque cursor
+func EncodeCursor(username string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(username))
+}
+
+// DecodeCursor decodes a cursor produced by EncodeCursor
+func DecodeCursor(cursor string) (string, error) {
+	username, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(username) == 0 {
+		return "", fmt.Errorf("%w: invalid cursor", ErrInvalidRequest)
+	}
+	return string(username), nil
+}
+
+func extractUsernameFromPath(path string) (string, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 2 {
+		return "", http.ErrNotSupported
+	}
+
+	username := parts[1]
+	if username == "" {
+		return "", http.ErrNotSupported
+	}
+
+	return username, nil
+}
+
+// writeError records an error on the span and writes the HTTP status mapped
+// from its error type; message is used for invalid requests and internal errors
+func writeError(w http.ResponseWriter, span trace.Span, err error, message string) {
+	recordError(span, err)
+
+	status := StatusCode(err)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		message = "User not found"
+	case errors.Is(err, ErrUsernameConflict):
+		message = "Username already exists"
+	case errors.Is(err, ErrEmailConflict):
+		message = "Email already exists"
+	case errors.Is(err, ErrValidation):
+		message = "Invalid user data"
+	case errors.Is(err, ErrUnavailable):
+		log.Printf("%s: %v", message, err)
+		message = "Service unavailable"
+	case errors.Is(err, ErrCanceled):
+		message = "Request canceled"
+	case errors.Is(err, ErrInvalidRequest), errors.Is(err, ErrMethodNotAllowed):
+		// keep the caller's message
+	default:
+		log.Printf("%s: %v", message, err)
+	}
+
+	http.Error(w, message, status)
+}
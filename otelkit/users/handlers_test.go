@@ -0,0 +1,100 @@
+package users
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestParseListUsersQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    ListUsersQuery
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  ListUsersQuery{Limit: defaultPageSize, Sort: "username"},
+		},
+		{
+			name:  "offset page with filters",
+			query: "limit=10&offset=20&name=jo&email=example&min_age=18&max_age=65",
+			want: ListUsersQuery{
+				Limit: 10, Offset: 20, Sort: "username",
+				Name: "jo", Email: "example", MinAge: 18, MaxAge: 65,
+			},
+		},
+		{
+			name:  "descending sort",
+			query: "sort=-age",
+			want:  ListUsersQuery{Limit: defaultPageSize, Sort: "age", Desc: true},
+		},
+		{
+			name:  "cursor",
+			query: "cursor=" + EncodeCursor("johndoe"),
+			want:  ListUsersQuery{Limit: defaultPageSize, Sort: "username", Cursor: "johndoe"},
+		},
+		{
+			name:  "descending cursor",
+			query: "sort=-username&cursor=" + EncodeCursor("johndoe"),
+			want:  ListUsersQuery{Limit: defaultPageSize, Sort: "username", Desc: true, Cursor: "johndoe"},
+		},
+		{name: "non-numeric limit", query: "limit=ten", wantErr: true},
+		{name: "negative offset", query: "offset=-1", wantErr: true},
+		{name: "zero limit", query: "limit=0", wantErr: true},
+		{name: "limit too large", query: "limit=501", wantErr: true},
+		{name: "negative min_age", query: "min_age=-5", wantErr: true},
+		{name: "min_age above max_age", query: "min_age=40&max_age=30", wantErr: true},
+		{name: "unsupported sort", query: "sort=password", wantErr: true},
+		{name: "cursor with other sort", query: "sort=age&cursor=" + EncodeCursor("johndoe"), wantErr: true},
+		{name: "cursor with offset", query: "offset=5&cursor=" + EncodeCursor("johndoe"), wantErr: true},
+		{name: "malformed cursor", query: "cursor=!!!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := parseListUsersQuery(values)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRequest) {
+					t.Fatalf("error = %v, want ErrInvalidRequest", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, username := range []string{"johndoe", "a", "user with spaces", "ünïcødé", "a/b+c="} {
+		cursor := EncodeCursor(username)
+		got, err := DecodeCursor(cursor)
+		if err != nil {
+			t.Errorf("DecodeCursor(EncodeCursor(%q)): %v", username, err)
+			continue
+		}
+		if got != username {
+			t.Errorf("round trip of %q = %q", username, got)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, cursor := range []string{"", "!!!", "Ym9ic21pdGg="} {
+		if _, err := DecodeCursor(cursor); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidRequest", cursor, err)
+		}
+	}
+}
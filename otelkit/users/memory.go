@@ -0,0 +1,309 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"otelkit/apmattr"
+	"otelkit/instrument"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// memorySystem is the db.system reported by MemoryStore spans
+const memorySystem = "memory"
+
+// memoryTable is the apm.db.table reported by MemoryStore spans, the table
+// the SQL statements of UserRepository operate on
+const memoryTable = "go_user_tbl"
+
+// Column sizes of go_user_tbl, enforced by MemoryStore like the database does
+const (
+	maxUsernameLength = 50
+	maxNameLength     = 100
+	maxEmailLength    = 100
+)
+
+// MemoryStore is a concurrency-safe in-memory UserStore with the same
+// semantics as UserRepository, for tests and running without a database
+type MemoryStore struct {
+	mu    sync.RWMutex
+	users map[string]User
+	instr *instrument.Instrumenter
+}
+
+// NewMemoryStore creates an empty in-memory user store
+func NewMemoryStore(instr *instrument.Instrumenter) *MemoryStore {
+	return &MemoryStore{users: map[string]User{}, instr: instr}
+}
+
+// CreateUser creates a new user in memory
+func (s *MemoryStore) CreateUser(ctx context.Context, req CreateUserRequest) (*User, error) {
+	_, span := s.instr.Start(ctx, "db:CreateUser")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.DBSystem.String(memorySystem),
+		semconv.DBSystemKey.String(memorySystem),
+		apmattr.DBOperation.String("INSERT"),
+		apmattr.DBTable.String(memoryTable),
+	)
+
+	if err := validateLengths(req.Username, req.Name, req.Email); err != nil {
+		return nil, fmt.Errorf("error creating user: %w", recordError(span, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[req.Username]; ok {
+		return nil, fmt.Errorf("error creating user: %w", recordError(span, ErrUsernameConflict))
+	}
+	if s.emailTaken(req.Email, "") {
+		return nil, fmt.Errorf("error creating user: %w", recordError(span, ErrEmailConflict))
+	}
+
+	now := time.Now()
+	user := User{
+		Username:  req.Username,
+		Name:      req.Name,
+		Email:     req.Email,
+		Age:       req.Age,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.users[user.Username] = user
+
+	return &user, nil
+}
+
+// GetUserByUsername retrieves a user by username
+func (s *MemoryStore) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	_, span := s.instr.Start(ctx, "db:GetUserByUsername")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.DBSystem.String(memorySystem),
+		semconv.DBSystemKey.String(memorySystem),
+		apmattr.DBOperation.String("SELECT"),
+		apmattr.DBTable.String(memoryTable),
+	)
+
+	s.mu.RLock()
+	user, ok := s.users[username]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("error getting user: %w", recordError(span, ErrUserNotFound))
+	}
+
+	return &user, nil
+}
+
+// GetAllUsers retrieves a page of users matching the query
+func (s *MemoryStore) GetAllUsers(ctx context.Context, q ListUsersQuery) (*UserPage, error) {
+	_, span := s.instr.Start(ctx, "db:GetAllUsers")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.DBSystem.String(memorySystem),
+		semconv.DBSystemKey.String(memorySystem),
+		apmattr.DBOperation.String("SELECT"),
+		apmattr.DBTable.String(memoryTable),
+	)
+
+	less, ok := userOrderings[q.Sort]
+	if !ok {
+		err := fmt.Errorf("%w: unsupported sort field %q", ErrInvalidRequest, q.Sort)
+		return nil, recordError(span, err)
+	}
+
+	var filters []string
+	if q.Name != "" {
+		filters = append(filters, "name")
+	}
+	if q.Email != "" {
+		filters = append(filters, "email")
+	}
+	if q.MinAge > 0 {
+		filters = append(filters, "min_age")
+	}
+	if q.MaxAge > 0 {
+		filters = append(filters, "max_age")
+	}
+
+	span.SetAttributes(
+		apmattr.DBPageSize.Int(q.Limit),
+		apmattr.DBFilters.StringSlice(filters),
+		apmattr.DBSort.String(q.Sort),
+	)
+
+	s.mu.RLock()
+	matched := make([]User, 0, len(s.users))
+	for _, user := range s.users {
+		if matchesFilters(user, q) {
+			matched = append(matched, user)
+		}
+	}
+	s.mu.RUnlock()
+
+	// Usernames are unique, so they break ties between equal sort values
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if q.Desc {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.Username < b.Username
+	})
+
+	page := &UserPage{Users: []User{}, Total: len(matched), Limit: q.Limit, Offset: q.Offset}
+
+	// Keyset pagination continues after the cursor username
+	if q.Cursor != "" {
+		start := sort.Search(len(matched), func(i int) bool {
+			if q.Desc {
+				return matched[i].Username < q.Cursor
+			}
+			return matched[i].Username > q.Cursor
+		})
+		matched = matched[start:]
+		span.SetAttributes(apmattr.DBPagination.String("cursor"))
+	} else {
+		span.SetAttributes(apmattr.DBPagination.String("offset"))
+	}
+
+	// Take one extra user to find out whether there is a next page
+	if q.Offset < len(matched) {
+		matched = matched[q.Offset:]
+		if len(matched) > q.Limit+1 {
+			matched = matched[:q.Limit+1]
+		}
+		page.Users = append(page.Users, matched...)
+	}
+
+	finishPage(page, q)
+
+	span.SetAttributes(apmattr.DBRowsReturned.Int(len(page.Users)))
+
+	return page, nil
+}
+
+// UpdateUser updates an existing user
+func (s *MemoryStore) UpdateUser(ctx context.Context, username string, req UpdateUserRequest) (*User, error) {
+	_, span := s.instr.Start(ctx, "db:UpdateUser")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.DBSystem.String(memorySystem),
+		semconv.DBSystemKey.String(memorySystem),
+		apmattr.DBOperation.String("UPDATE"),
+		apmattr.DBTable.String(memoryTable),
+	)
+
+	if err := validateLengths(username, req.Name, req.Email); err != nil {
+		return nil, fmt.Errorf("error updating user: %w", recordError(span, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[username]
+	if !ok {
+		return nil, fmt.Errorf("error updating user: %w", recordError(span, ErrUserNotFound))
+	}
+	if s.emailTaken(req.Email, username) {
+		return nil, fmt.Errorf("error updating user: %w", recordError(span, ErrEmailConflict))
+	}
+
+	user.Name = req.Name
+	user.Email = req.Email
+	user.Age = req.Age
+	user.UpdatedAt = time.Now()
+	s.users[username] = user
+
+	return &user, nil
+}
+
+// DeleteUser deletes a user by username
+func (s *MemoryStore) DeleteUser(ctx context.Context, username string) error {
+	_, span := s.instr.Start(ctx, "db:DeleteUser")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.DBSystem.String(memorySystem),
+		semconv.DBSystemKey.String(memorySystem),
+		apmattr.DBOperation.String("DELETE"),
+		apmattr.DBTable.String(memoryTable),
+	)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[username]; !ok {
+		return recordError(span, ErrUserNotFound)
+	}
+	delete(s.users, username)
+
+	return nil
+}
+
+// emailTaken reports whether a user other than except uses email; s.mu must be held
+func (s *MemoryStore) emailTaken(email, except string) bool {
+	for username, user := range s.users {
+		if user.Email == email && username != except {
+			return true
+		}
+	}
+	return false
+}
+
+// userOrderings are the less functions for the sortable fields of GET /users
+var userOrderings = map[string]func(a, b User) bool{
+	"username":   func(a, b User) bool { return a.Username < b.Username },
+	"name":       func(a, b User) bool { return a.Name < b.Name },
+	"email":      func(a, b User) bool { return a.Email < b.Email },
+	"age":        func(a, b User) bool { return a.Age < b.Age },
+	"created_at": func(a, b User) bool { return a.CreatedAt.Before(b.CreatedAt) },
+	"updated_at": func(a, b User) bool { return a.UpdatedAt.Before(b.UpdatedAt) },
+}
+
+// matchesFilters applies the GET /users filters the way the SQL query does
+func matchesFilters(user User, q ListUsersQuery) bool {
+	if q.Name != "" && !strings.Contains(strings.ToLower(user.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+	if q.Email != "" && !strings.Contains(strings.ToLower(user.Email), strings.ToLower(q.Email)) {
+		return false
+	}
+	if q.MinAge > 0 && user.Age < q.MinAge {
+		return false
+	}
+	if q.MaxAge > 0 && user.Age > q.MaxAge {
+		return false
+	}
+	return true
+}
+
+// validateLengths rejects values that do not fit the go_user_tbl columns
+func validateLengths(username, name, email string) error {
+	switch {
+	case utf8.RuneCountInString(username) > maxUsernameLength:
+		return fmt.Errorf("%w: username longer than %d characters", ErrValidation, maxUsernameLength)
+	case utf8.RuneCountInString(name) > maxNameLength:
+		return fmt.Errorf("%w: name longer than %d characters", ErrValidation, maxNameLength)
+	case utf8.RuneCountInString(email) > maxEmailLength:
+		return fmt.Errorf("%w: email longer than %d characters", ErrValidation, maxEmailLength)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"otelkit/instrument"
+)
+
+func newTestMemoryStore(t *testing.T, seed ...CreateUserRequest) *MemoryStore {
+	t.Helper()
+
+	store := NewMemoryStore(instrument.New("test", instrument.ModeChildSpan))
+	for _, req := range seed {
+		if _, err := store.CreateUser(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestMemoryStoreCRUD(t *testing.T) {
+	ctx := context.Background()
+	store := newTestMemoryStore(t)
+
+	created, err := store.CreateUser(ctx, CreateUserRequest{Username: "johndoe", Name: "John Doe", Email: "john@example.com", Age: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.CreatedAt.IsZero() || !created.CreatedAt.Equal(created.UpdatedAt) {
+		t.Errorf("timestamps = %v / %v, want equal non-zero", created.CreatedAt, created.UpdatedAt)
+	}
+
+	got, err := store.GetUserByUsername(ctx, "johndoe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *created {
+		t.Errorf("GetUserByUsername = %+v, want %+v", got, created)
+	}
+
+	updated, err := store.UpdateUser(ctx, "johndoe", UpdateUserRequest{Name: "John", Email: "john@example.org", Age: 31})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "John" || updated.Age != 31 || !updated.CreatedAt.Equal(created.CreatedAt) || updated.UpdatedAt.Before(created.UpdatedAt) {
+		t.Errorf("UpdateUser = %+v", updated)
+	}
+
+	if err := store.DeleteUser(ctx, "johndoe"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetUserByUsername(ctx, "johndoe"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("GetUserByUsername after delete error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestMemoryStoreErrors(t *testing.T) {
+	ctx := context.Background()
+	store := newTestMemoryStore(t,
+		CreateUserRequest{Username: "johndoe", Name: "John Doe", Email: "john@example.com", Age: 30},
+		CreateUserRequest{Username: "janedoe", Name: "Jane Doe", Email: "jane@example.com", Age: 28},
+	)
+
+	tests := []struct {
+		name string
+		run  func() error
+		want error
+	}{
+		{"duplicate username", func() error {
+			_, err := store.CreateUser(ctx, CreateUserRequest{Username: "johndoe", Name: "J", Email: "other@example.com", Age: 1})
+			return err
+		}, ErrUsernameConflict},
+		{"duplicate email", func() error {
+			_, err := store.CreateUser(ctx, CreateUserRequest{Username: "jd", Name: "J", Email: "john@example.com", Age: 1})
+			return err
+		}, ErrEmailConflict},
+		{"username too long", func() error {
+			_, err := store.CreateUser(ctx, CreateUserRequest{Username: strings.Repeat("u", 51), Name: "J", Email: "u@example.com", Age: 1})
+			return err
+		}, ErrValidation},
+		{"update to taken email", func() error {
+			_, err := store.UpdateUser(ctx, "janedoe", UpdateUserRequest{Name: "Jane", Email: "john@example.com", Age: 28})
+			return err
+		}, ErrEmailConflict},
+		{"update missing user", func() error {
+			_, err := store.UpdateUser(ctx, "nobody", UpdateUserRequest{Name: "N", Email: "n@example.com", Age: 1})
+			return err
+		}, ErrUserNotFound},
+		{"delete missing user", func() error {
+			return store.DeleteUser(ctx, "nobody")
+		}, ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Keeping your own email on update is not a conflict
+	if _, err := store.UpdateUser(ctx, "janedoe", UpdateUserRequest{Name: "Jane", Email: "jane@example.com", Age: 29}); err != nil {
+		t.Errorf("UpdateUser with unchanged email: %v", err)
+	}
+}
+
+func TestMemoryStoreGetAllUsers(t *testing.T) {
+	ctx := context.Background()
+	store := newTestMemoryStore(t,
+		CreateUserRequest{Username: "alicejones", Name: "Alice Jones", Email: "alice.jones@example.com", Age: 25},
+		CreateUserRequest{Username: "bobsmith", Name: "Bob Smith", Email: "bob.smith@example.com", Age: 35},
+		CreateUserRequest{Username: "charliebrwn", Name: "Charlie Brown", Email: "charlie.brown@example.com", Age: 32},
+		CreateUserRequest{Username: "janedoe", Name: "Jane Doe", Email: "jane.doe@example.com", Age: 28},
+		CreateUserRequest{Username: "johndoe", Name: "John Doe", Email: "john.doe@example.com", Age: 30},
+	)
+
+	usernames := func(page *UserPage) string {
+		names := make([]string, len(page.Users))
+		for i, u := range page.Users {
+			names[i] = u.Username
+		}
+		return strings.Join(names, ",")
+	}
+
+	tests := []struct {
+		name  string
+		query ListUsersQuery
+		want  string
+		total int
+		more  bool
+	}{
+		{"first page", ListUsersQuery{Limit: 2, Sort: "username"}, "alicejones,bobsmith", 5, true},
+		{"offset page", ListUsersQuery{Limit: 2, Offset: 4, Sort: "username"}, "johndoe", 5, false},
+		{"cursor page", ListUsersQuery{Limit: 2, Sort: "username", Cursor: "bobsmith"}, "charliebrwn,janedoe", 5, true},
+		{"descending cursor", ListUsersQuery{Limit: 2, Sort: "username", Desc: true, Cursor: "janedoe"}, "charliebrwn,bobsmith", 5, true},
+		{"sort by age", ListUsersQuery{Limit: 10, Sort: "age", Desc: true}, "bobsmith,charliebrwn,johndoe,janedoe,alicejones", 5, false},
+		{"name filter", ListUsersQuery{Limit: 10, Sort: "username", Name: "DOE"}, "janedoe,johndoe", 2, false},
+		{"age range", ListUsersQuery{Limit: 10, Sort: "username", MinAge: 28, MaxAge: 32}, "charliebrwn,janedoe,johndoe", 3, false},
+		{"past the end", ListUsersQuery{Limit: 10, Offset: 10, Sort: "username"}, "", 5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := store.GetAllUsers(ctx, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := usernames(page); got != tt.want {
+				t.Errorf("users = %s, want %s", got, tt.want)
+			}
+			if page.Total != tt.total || page.HasMore != tt.more {
+				t.Errorf("total, has_more = %d, %v; want %d, %v", page.Total, page.HasMore, tt.total, tt.more)
+			}
+		})
+	}
+
+	if _, err := store.GetAllUsers(ctx, ListUsersQuery{Limit: 1, Sort: "password"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("unsupported sort error = %v, want ErrInvalidRequest", err)
+	}
+}
+
+func TestMemoryStoreConcurrentCreate(t *testing.T) {
+	ctx := context.Background()
+	store := newTestMemoryStore(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := store.CreateUser(ctx, CreateUserRequest{
+				Username: "same", Name: "Same", Email: fmt.Sprintf("same%d@example.com", i), Age: 20,
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, ErrUsernameConflict):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("created %d users with the same username, want 1", created)
+	}
+}
@@ -0,0 +1,29 @@
+package users
+
+import (
+	"embed"
+
+	"otelkit/migrate"
+)
+
+// migrationFiles holds the versioned go_user_tbl schema migrations for
+// PostgreSQL, and for SQLite in the sqlite directory
+//
+//go:embed migrations/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// NewMigrator creates a migrator for the embedded user store migrations of
+// the database system
+func NewMigrator(db *Database) (*migrate.Migrator, error) {
+	dir, dialect := "migrations", migrate.Postgres
+	if db.System == SystemSQLite {
+		dir, dialect = "migrations/sqlite", migrate.SQLite
+	}
+
+	migrations, err := migrate.Load(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.New(db.DB, migrations).WithDialect(dialect), nil
+}
@@ -0,0 +1,10 @@
+-- SQLite does not enforce VARCHAR lengths, so the CHECK constraints reject
+-- the values PostgreSQL would
+CREATE TABLE IF NOT EXISTS go_user_tbl (
+	username VARCHAR(50) PRIMARY KEY CHECK (length(username) <= 50),
+	name VARCHAR(100) NOT NULL CHECK (length(name) <= 100),
+	email VARCHAR(100) UNIQUE NOT NULL CHECK (length(email) <= 100),
+	age INTEGER NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+DELETE FROM go_user_tbl
+WHERE username IN ('johndoe', 'janedoe', 'bobsmith', 'alicejones', 'charliebrwn');
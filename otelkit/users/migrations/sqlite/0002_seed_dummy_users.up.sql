@@ -0,0 +1,11 @@
+-- Dummy users for local development, only inserted into an empty table
+INSERT INTO go_user_tbl (username, name, email, age, created_at, updated_at)
+SELECT column1, column2, column3, column4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+FROM (VALUES
+	('johndoe', 'John Doe', 'john.doe@example.com', 30),
+	('janedoe', 'Jane Doe', 'jane.doe@example.com', 28),
+	('bobsmith', 'Bob Smith', 'bob.smith@example.com', 35),
+	('alicejones', 'Alice Jones', 'alice.jones@example.com', 25),
+	('charliebrwn', 'Charlie Brown', 'charlie.brown@example.com', 32)
+)
+WHERE NOT EXISTS (SELECT 1 FROM go_user_tbl);
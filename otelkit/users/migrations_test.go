@@ -0,0 +1,23 @@
+package users
+
+import (
+	"testing"
+
+	"otelkit/migrate"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := migrate.Load(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) == 0 || migrations[0].Name != "create_go_user_tbl" {
+		t.Fatalf("first migration = %+v, want create_go_user_tbl", migrations)
+	}
+	for _, m := range migrations {
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down migration", m.Version, m.Name)
+		}
+	}
+}
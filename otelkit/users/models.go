@@ -0,0 +1,67 @@
+// Package users implements the user management API shared by otelapi and
+// oteltracer: models, database access, repository, handlers and routing.
+package users
+
+import (
+	"time"
+)
+
+// User represents a user in the system
+type User struct {
+	Username  string    `json:"username" example:"johndoe"`
+	Name      string    `json:"name" example:"John Doe"`
+	Email     string    `json:"email" example:"john.doe@example.com"`
+	Age       int       `json:"age" example:"30"`
+	CreatedAt time.Time `json:"created_at" example:"2023-10-27T10:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2023-10-27T10:00:00Z"`
+}
+
+// CreateUserRequest represents the request body for creating a user
+type CreateUserRequest struct {
+	Username string `json:"username" example:"johndoe"`
+	Name     string `json:"name" example:"John Doe"`
+	Email    string `json:"email" example:"john.doe@example.com"`
+	Age      int    `json:"age" example:"30"`
+}
+
+// UpdateUserRequest represents the request body for updating a user
+type UpdateUserRequest struct {
+	Name  string `json:"name" example:"John Doe Updated"`
+	Email string `json:"email" example:"john.doe.updated@example.com"`
+	Age   int    `json:"age" example:"31"`
+}
+
+// ListUsersQuery holds the pagination, sorting and filtering options for GET /users
+type ListUsersQuery struct {
+	// Limit is the page size
+	Limit int
+	// Offset skips rows for offset pagination
+	Offset int
+	// Cursor is the username after which keyset pagination continues
+	Cursor string
+	// Sort is the column to order by
+	Sort string
+	// Desc orders descending
+	Desc bool
+	// Name filters on a case-insensitive name substring
+	Name string
+	// Email filters on a case-insensitive email substring
+	Email string
+	// MinAge filters on age >= MinAge when non-zero
+	MinAge int
+	// MaxAge filters on age <= MaxAge when non-zero
+	MaxAge int
+}
+
+// UserPage represents a page of users returned by GET /users. HasMore
+// reports whether another page follows; it is continued with NextCursor for
+// keyset pagination and NextOffset otherwise.
+type UserPage struct {
+	Users      []User `json:"users"`
+	Total      int    `json:"total" example:"42"`
+	Limit      int    `json:"limit" example:"50"`
+	Offset     int    `json:"offset" example:"0"`
+	HasMore    bool   `json:"has_more" example:"true"`
+	NextCursor string `json:"next_cursor,omitempty" example:"Ym9ic21pdGg"`
+	NextOffset int    `json:"next_offset,omitempty" example:"50"`
+}
@@ -0,0 +1,348 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"otelkit/apmattr"
+	"otelkit/instrument"
+	"otelkit/sqltrace"
+)
+
+// Statements of UserRepository
+const (
+	createUserQuery = `
+		INSERT INTO go_user_tbl (username, name, email, age, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING username, name, email, age, created_at, updated_at
+	`
+	getUserQuery = `
+		SELECT username, name, email, age, created_at, updated_at
+		FROM go_user_tbl
+		WHERE username = $1
+	`
+	countUsersQuery = "SELECT COUNT(*) FROM go_user_tbl "
+	updateUserQuery = `
+		UPDATE go_user_tbl
+		SET name = $1, email = $2, age = $3, updated_at = $4
+		WHERE username = $5
+		RETURNING username, name, email, age, created_at, updated_at
+	`
+	deleteUserQuery = `DELETE FROM go_user_tbl WHERE username = $1`
+)
+
+// Tables of the statements, reported as apm.db.table; derived once rather
+// than on every call
+var (
+	createUserTable = sqltrace.Normalize(createUserQuery).Table
+	getUserTable    = sqltrace.Normalize(getUserQuery).Table
+	countUsersTable = sqltrace.Normalize(countUsersQuery).Table
+	updateUserTable = sqltrace.Normalize(updateUserQuery).Table
+	deleteUserTable = sqltrace.Normalize(deleteUserQuery).Table
+)
+
+// UserRepository is the SQL UserStore, backed by PostgreSQL or SQLite. The
+// statements are traced by the sqltrace driver wrapper of the Database; the
+// operation spans started here carry the business attributes only.
+type UserRepository struct {
+	db    *Database
+	instr *instrument.Instrumenter
+}
+
+// NewUserRepository creates a new user repository
+func NewUserRepository(db *Database, instr *instrument.Instrumenter) *UserRepository {
+	return &UserRepository{db: db, instr: instr}
+}
+
+// CreateUser creates a new user in the database
+func (r *UserRepository) CreateUser(ctx context.Context, req CreateUserRequest) (*User, error) {
+	ctx, span := r.instr.Start(ctx, "db:CreateUser")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.DBSystem.String(r.db.System),
+		apmattr.DBTable.String(createUserTable),
+	)
+
+	conn, err := r.db.conn(ctx, span)
+	if err != nil {
+		err = recordError(span, classifyError(err))
+		return nil, fmt.Errorf("error creating user: %w", err)
+	}
+	defer conn.Close()
+
+	now := time.Now()
+	user := &User{}
+
+	err = conn.QueryRowContext(ctx, r.db.rebind(createUserQuery), req.Username, req.Name, req.Email, req.Age, now, now).Scan(
+		&user.Username,
+		&user.Name,
+		&user.Email,
+		&user.Age,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		err = recordError(span, classifyError(err))
+		return nil, fmt.Errorf("error creating user: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetUserByUsername retrieves a user by username
+func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	ctx, span := r.instr.Start(ctx, "db:GetUserByUsername")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.DBSystem.String(r.db.System),
+		apmattr.DBTable.String(getUserTable),
+	)
+
+	conn, err := r.db.conn(ctx, span)
+	if err != nil {
+		err = recordError(span, classifyError(err))
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	defer conn.Close()
+
+	user := &User{}
+	err = conn.QueryRowContext(ctx, r.db.rebind(getUserQuery), username).Scan(
+		&user.Username,
+		&user.Name,
+		&user.Email,
+		&user.Age,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		err = recordError(span, classifyError(err))
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	return user, nil
+}
+
+// sortColumns maps the sortable fields of GET /users to their columns
+var sortColumns = map[string]string{
+	"username":   "username",
+	"name":       "name",
+	"email":      "email",
+	"age":        "age",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// GetAllUsers retrieves a page of users matching the query from the database
+func (r *UserRepository) GetAllUsers(ctx context.Context, q ListUsersQuery) (*UserPage, error) {
+	ctx, span := r.instr.Start(ctx, "db:GetAllUsers")
+	defer span.End()
+
+	span.SetAttributes(apmattr.DBSystem.String(r.db.System))
+
+	column, ok := sortColumns[q.Sort]
+	if !ok {
+		err := fmt.Errorf("%w: unsupported sort field %q", ErrInvalidRequest, q.Sort)
+		return nil, recordError(span, err)
+	}
+
+	direction := "ASC"
+	if q.Desc {
+		direction = "DESC"
+	}
+
+	// Filters shared by the count and page queries
+	var conditions, filters []string
+	var args []interface{}
+	addCondition := func(filter, condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+		filters = append(filters, filter)
+	}
+
+	if q.Name != "" {
+		addCondition("name", r.db.caseInsensitiveLike("name"), "%"+escapeLike(q.Name)+"%")
+	}
+	if q.Email != "" {
+		addCondition("email", r.db.caseInsensitiveLike("email"), "%"+escapeLike(q.Email)+"%")
+	}
+	if q.MinAge > 0 {
+		addCondition("min_age", "age >= $%d", q.MinAge)
+	}
+	if q.MaxAge > 0 {
+		addCondition("max_age", "age <= $%d", q.MaxAge)
+	}
+
+	span.SetAttributes(
+		apmattr.DBPageSize.Int(q.Limit),
+		apmattr.DBFilters.StringSlice(filters),
+		apmattr.DBSort.String(q.Sort),
+	)
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	conn, err := r.db.conn(ctx, span)
+	if err != nil {
+		err = recordError(span, classifyError(err))
+		return nil, fmt.Errorf("error counting users: %w", err)
+	}
+	defer conn.Close()
+
+	page := &UserPage{Users: []User{}, Limit: q.Limit, Offset: q.Offset}
+
+	countQuery := countUsersQuery + where
+	span.SetAttributes(apmattr.DBTable.String(countUsersTable))
+	if err := conn.QueryRowContext(ctx, r.db.rebind(countQuery), args...).Scan(&page.Total); err != nil {
+		err = recordError(span, classifyError(err))
+		return nil, fmt.Errorf("error counting users: %w", err)
+	}
+
+	// Keyset pagination continues after the cursor username
+	if q.Cursor != "" {
+		operator := ">"
+		if q.Desc {
+			operator = "<"
+		}
+		args = append(args, q.Cursor)
+		conditions = append(conditions, fmt.Sprintf("username %s $%d", operator, len(args)))
+		where = "WHERE " + strings.Join(conditions, " AND ")
+		span.SetAttributes(apmattr.DBPagination.String("cursor"))
+	} else {
+		span.SetAttributes(apmattr.DBPagination.String("offset"))
+	}
+
+	// Usernames are unique, so they break ties between equal sort values
+	orderBy := column + " " + direction
+	if column != "username" {
+		orderBy += ", username " + direction
+	}
+
+	// Fetch one extra row to find out whether there is a next page
+	args = append(args, q.Limit+1, q.Offset)
+	query := fmt.Sprintf(`
+		SELECT username, name, email, age, created_at, updated_at
+		FROM go_user_tbl
+		%s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, where, orderBy, len(args)-1, len(args))
+
+	rows, err := conn.QueryContext(ctx, r.db.rebind(query), args...)
+	if err != nil {
+		err = recordError(span, classifyError(err))
+		return nil, fmt.Errorf("error querying users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user User
+		err := rows.Scan(
+			&user.Username,
+			&user.Name,
+			&user.Email,
+			&user.Age,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		)
+		if err != nil {
+			err = recordError(span, classifyError(err))
+			return nil, fmt.Errorf("error scanning user: %w", err)
+		}
+		page.Users = append(page.Users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = recordError(span, classifyError(err))
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	finishPage(page, q)
+
+	span.SetAttributes(apmattr.DBRowsReturned.Int(len(page.Users)))
+
+	return page, nil
+}
+
+// escapeLike escapes the LIKE wildcards in a filter value
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// UpdateUser updates an existing user
+func (r *UserRepository) UpdateUser(ctx context.Context, username string, req UpdateUserRequest) (*User, error) {
+	ctx, span := r.instr.Start(ctx, "db:UpdateUser")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.DBSystem.String(r.db.System),
+		apmattr.DBTable.String(updateUserTable),
+	)
+
+	conn, err := r.db.conn(ctx, span)
+	if err != nil {
+		err = recordError(span, classifyError(err))
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+	defer conn.Close()
+
+	user := &User{}
+	err = conn.QueryRowContext(ctx, r.db.rebind(updateUserQuery), req.Name, req.Email, req.Age, time.Now(), username).Scan(
+		&user.Username,
+		&user.Name,
+		&user.Email,
+		&user.Age,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		err = recordError(span, classifyError(err))
+		return nil, fmt.Errorf("error updating user: %w", err)
+	}
+
+	return user, nil
+}
+
+// DeleteUser deletes a user by username
+func (r *UserRepository) DeleteUser(ctx context.Context, username string) error {
+	ctx, span := r.instr.Start(ctx, "db:DeleteUser")
+	defer span.End()
+
+	span.SetAttributes(
+		apmattr.DBSystem.String(r.db.System),
+		apmattr.DBTable.String(deleteUserTable),
+	)
+
+	conn, err := r.db.conn(ctx, span)
+	if err != nil {
+		err = recordError(span, classifyError(err))
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+	defer conn.Close()
+
+	result, err := conn.ExecContext(ctx, r.db.rebind(deleteUserQuery), username)
+	if err != nil {
+		err = recordError(span, classifyError(err))
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		err = recordError(span, classifyError(err))
+		return fmt.Errorf("error checking rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return recordError(span, ErrUserNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package users
+
+import (
+	"net/http"
+	"strings"
+
+	"otelkit/apmattr"
+	"otelkit/telemetry"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracedUserHandler routes /users and /users/{username} requests to UserHandler
+// and enriches the active span with the matched route, handler and response status
+type TracedUserHandler struct {
+	users *UserHandler
+}
+
+// NewTracedUserHandler creates a new traced user router
+func NewTracedUserHandler(users *UserHandler) *TracedUserHandler {
+	return &TracedUserHandler{users: users}
+}
+
+// ServeHTTP dispatches the request by path and method, mirroring the otelapi router
+func (h *TracedUserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	span := trace.SpanFromContext(r.Context())
+
+	route := "/users"
+	username := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/users/"), "/")
+
+	// Only /users and /users/{username} exist; deeper paths are not routed
+	if strings.Contains(username, "/") {
+		span.SetAttributes(apmattr.HTTPStatusCode.Int(http.StatusNotFound))
+		http.NotFound(w, r)
+		return
+	}
+
+	if username != "" {
+		route = "/users/{username}"
+		r.SetPathValue("username", username)
+	}
+
+	name, handle := h.match(r.Method, username)
+	telemetry.SetRoute(r.Context(), route)
+
+	span.SetAttributes(
+		apmattr.HTTPRoute.String(route),
+		apmattr.HTTPHandler.String(name),
+	)
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	handle(rec, r)
+
+	span.SetAttributes(apmattr.HTTPStatusCode.Int(rec.status))
+
+	// Per the HTTP semantic conventions only 5xx responses fail a server span
+	if rec.status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(rec.status))
+	}
+}
+
+// match returns the UserHandler method for the given method and username
+func (h *TracedUserHandler) match(method, username string) (string, http.HandlerFunc) {
+	if username == "" {
+		// /users endpoint
+		switch method {
+		case http.MethodGet:
+			return "GetAllUsers", h.users.GetAllUsers
+		case http.MethodPost:
+			return "CreateUser", h.users.CreateUser
+		}
+	} else {
+		// /users/{username} endpoint
+		switch method {
+		case http.MethodGet:
+			return "GetUser", h.users.GetUser
+		case http.MethodPut:
+			return "UpdateUser", h.users.UpdateUser
+		case http.MethodDelete:
+			return "DeleteUser", h.users.DeleteUser
+		}
+	}
+
+	return "MethodNotAllowed", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, trace.SpanFromContext(r.Context()), ErrMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// statusRecorder captures the status code written by the wrapped handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader records the status code before delegating
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status = code
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Write marks the header as written with the implicit 200 status
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
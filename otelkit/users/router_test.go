@@ -0,0 +1,154 @@
+package users
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"otelkit/instrument"
+	"otelkit/spantest"
+	"otelkit/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// serveTraced drives the traced user router with a server span around the
+// request, standing in for the span otelhttp or eBPF would start
+func serveTraced(t *testing.T, mode instrument.Mode, store UserStore, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	router := NewTracedUserHandler(NewUserHandler(store, instrument.New("test", mode)))
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	ctx, server := otel.Tracer("test").Start(req.Context(), "server", trace.WithSpanKind(trace.SpanKindServer))
+	defer server.End()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req.WithContext(ctx))
+	return w
+}
+
+func TestTracedUserHandlerChildSpans(t *testing.T) {
+	rec := spantest.New(t)
+	store := newTestMemoryStore(t, CreateUserRequest{Username: "johndoe", Name: "John Doe", Email: "john@example.com", Age: 30})
+	rec.Reset()
+
+	w := serveTraced(t, instrument.ModeChildSpan, store, http.MethodGet, "/users/johndoe", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	rec.Span("server").
+		IsRoot().
+		HasAttribute("apm.http.route", "/users/{username}").
+		HasAttribute("apm.http.handler", "GetUser").
+		HasAttribute("apm.http.status_code", http.StatusOK)
+	rec.Span("GetUser").
+		ChildOf("server").
+		HasAttribute("apm.operation", "get_user").
+		HasAttribute("apm.user.username", "johndoe")
+	rec.Span("db:GetUserByUsername").
+		ChildOf("GetUser").
+		HasAttribute("apm.db.table", "go_user_tbl").
+		HasAttribute("apm.db.operation", "SELECT").
+		HasAttribute("db.system", memorySystem).
+		HasStatus(codes.Unset)
+}
+
+func TestTracedUserHandlerEnrich(t *testing.T) {
+	rec := spantest.New(t)
+	store := NewMemoryStore(instrument.New("test", instrument.ModeEnrich))
+	if _, err := store.CreateUser(context.Background(), CreateUserRequest{Username: "johndoe", Name: "John Doe", Email: "john@example.com", Age: 30}); err != nil {
+		t.Fatal(err)
+	}
+	rec.Reset()
+
+	serveTraced(t, instrument.ModeEnrich, store, http.MethodGet, "/users/johndoe", "")
+
+	// Every custom attribute lands on the active server span
+	rec.NoSpan("GetUser")
+	rec.NoSpan("db:GetUserByUsername")
+	rec.Span("server").
+		HasAttribute("apm.operation", "get_user").
+		HasAttribute("apm.db.table", "go_user_tbl").
+		HasAttribute("apm.http.status_code", http.StatusOK)
+}
+
+func TestTracedUserHandlerErrors(t *testing.T) {
+	rec := spantest.New(t)
+	store := newTestMemoryStore(t, CreateUserRequest{Username: "johndoe", Name: "John Doe", Email: "john@example.com", Age: 30})
+	rec.Reset()
+
+	body := `{"username":"johndoe","name":"John","email":"other@example.com","age":30}`
+	w := serveTraced(t, instrument.ModeChildSpan, store, http.MethodPost, "/users/", body)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409", w.Code)
+	}
+
+	// The repository span owns the exception event; a 4xx does not fail spans
+	rec.Span("db:CreateUser").
+		ChildOf("CreateUser").
+		HasEvent("exception").
+		HasAttribute("error.type", "username_conflict").
+		HasStatus(codes.Unset)
+	rec.Span("CreateUser").
+		HasAttribute("apm.error.status_code", http.StatusConflict).
+		HasStatus(codes.Unset)
+	rec.Span("server").
+		HasAttribute("apm.http.route", "/users").
+		HasAttribute("apm.http.status_code", http.StatusConflict)
+}
+
+func TestTracedUserHandlerNestedPath(t *testing.T) {
+	rec := spantest.New(t)
+
+	w := serveTraced(t, instrument.ModeChildSpan, NewMemoryStore(instrument.New("test", instrument.ModeChildSpan)), http.MethodGet, "/users/a/b", "")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", w.Code)
+	}
+
+	rec.NoSpan("GetUser")
+	rec.Span("server").
+		HasAttribute("apm.http.status_code", http.StatusNotFound).
+		NoAttribute("apm.http.route")
+}
+
+func TestServerMiddlewareContinuesTrace(t *testing.T) {
+	rec := spantest.New(t)
+	store := newTestMemoryStore(t, CreateUserRequest{Username: "johndoe", Name: "John Doe", Email: "john@example.com", Age: 30})
+	rec.Reset()
+
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	handler := telemetry.ServerMiddleware(NewTracedUserHandler(NewUserHandler(store, instrument.New("test", instrument.ModeChildSpan))))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/johndoe", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	server := rec.Span("GET /users/{username}").
+		HasAttribute("http.route", "/users/{username}").
+		HasAttribute("http.request.method", "GET").
+		HasAttribute("http.response.status_code", http.StatusOK).
+		HasStatus(codes.Unset).
+		ReadOnly()
+	if server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span kind = %v, want server", server.SpanKind())
+	}
+	if got := server.Parent(); !got.IsRemote() || got.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || got.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("server span parent = %v, want the caller's span", got)
+	}
+
+	rec.Span("GetUser").ChildOf("GET /users/{username}")
+}
@@ -0,0 +1,318 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"otelkit/instrument"
+	"otelkit/spantest"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// newTestSQLiteRepository opens a migrated SQLite database in a temporary directory
+func newTestSQLiteRepository(t *testing.T) (*UserRepository, *Database) {
+	t.Helper()
+
+	db, err := NewSQLiteDatabase(filepath.Join(t.TempDir(), "users.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewUserRepository(db, instrument.New("test", instrument.ModeChildSpan)), db
+}
+
+func TestSQLiteRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestSQLiteRepository(t)
+
+	// The seed migration inserts the dummy users
+	seeded, err := repo.GetUserByUsername(ctx, "johndoe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seeded.Email != "john.doe@example.com" || seeded.CreatedAt.IsZero() {
+		t.Errorf("seeded user = %+v", seeded)
+	}
+
+	created, err := repo.CreateUser(ctx, CreateUserRequest{Username: "newuser", Name: "New User", Email: "new@example.com", Age: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Username != "newuser" || created.CreatedAt.IsZero() {
+		t.Errorf("CreateUser = %+v", created)
+	}
+
+	updated, err := repo.UpdateUser(ctx, "newuser", UpdateUserRequest{Name: "Renamed", Email: "renamed@example.com", Age: 41})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "Renamed" || updated.Age != 41 {
+		t.Errorf("UpdateUser = %+v", updated)
+	}
+
+	if err := repo.DeleteUser(ctx, "newuser"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetUserByUsername(ctx, "newuser"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("GetUserByUsername after delete error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestSQLiteRepositoryErrors(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestSQLiteRepository(t)
+
+	tests := []struct {
+		name string
+		run  func() error
+		want error
+	}{
+		{"duplicate username", func() error {
+			_, err := repo.CreateUser(ctx, CreateUserRequest{Username: "johndoe", Name: "J", Email: "other@example.com", Age: 1})
+			return err
+		}, ErrUsernameConflict},
+		{"duplicate email", func() error {
+			_, err := repo.CreateUser(ctx, CreateUserRequest{Username: "jd", Name: "J", Email: "john.doe@example.com", Age: 1})
+			return err
+		}, ErrEmailConflict},
+		{"username too long", func() error {
+			_, err := repo.CreateUser(ctx, CreateUserRequest{Username: strings.Repeat("u", 51), Name: "J", Email: "u@example.com", Age: 1})
+			return err
+		}, ErrValidation},
+		{"update to taken email", func() error {
+			_, err := repo.UpdateUser(ctx, "janedoe", UpdateUserRequest{Name: "Jane", Email: "john.doe@example.com", Age: 28})
+			return err
+		}, ErrEmailConflict},
+		{"update missing user", func() error {
+			_, err := repo.UpdateUser(ctx, "nobody", UpdateUserRequest{Name: "N", Email: "n@example.com", Age: 1})
+			return err
+		}, ErrUserNotFound},
+		{"delete missing user", func() error {
+			return repo.DeleteUser(ctx, "nobody")
+		}, ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLiteRepositoryGetAllUsers(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestSQLiteRepository(t)
+
+	page, err := repo.GetAllUsers(ctx, ListUsersQuery{Limit: 2, Sort: "username", Name: "DOE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || len(page.Users) != 2 || page.Users[0].Username != "janedoe" || page.HasMore {
+		t.Errorf("name filter page = %+v", page)
+	}
+
+	page, err = repo.GetAllUsers(ctx, ListUsersQuery{Limit: 2, Sort: "age", Desc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Users[0].Username != "bobsmith" || !page.HasMore || page.NextOffset != 2 {
+		t.Errorf("sorted page = %+v", page)
+	}
+
+	// A literal % in the filter is not a wildcard
+	page, err = repo.GetAllUsers(ctx, ListUsersQuery{Limit: 10, Sort: "username", Name: "%"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 0 {
+		t.Errorf("escaped filter matched %d users, want 0", page.Total)
+	}
+}
+
+func TestSQLiteRepositoryTracesStatements(t *testing.T) {
+	repo, _ := newTestSQLiteRepository(t)
+	rec := spantest.New(t)
+
+	if err := repo.DeleteUser(context.Background(), "johndoe"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec.Span("DELETE go_user_tbl").
+		ChildOf("db:DeleteUser").
+		HasAttribute("db.system", SystemSQLite).
+		HasAttribute("db.statement", "DELETE FROM go_user_tbl WHERE username = ?").
+		HasAttribute("db.sql.table", "go_user_tbl").
+		HasAttribute("apm.db.rows_affected", 1)
+	rec.Span("db:DeleteUser").
+		HasAttribute("apm.db.table", "go_user_tbl").
+		HasAttributeKey("apm.db.connection.wait_ms").
+		NoAttribute("db.system").
+		NoAttribute("apm.db.query.parameter.username")
+}
+
+func TestSQLiteRepositoryCanceledRequest(t *testing.T) {
+	repo, _ := newTestSQLiteRepository(t)
+	rec := spantest.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.GetUserByUsername(ctx, "johndoe"); !errors.Is(err, ErrCanceled) {
+		t.Fatalf("GetUserByUsername() error = %v, want ErrCanceled", err)
+	}
+
+	// A client disconnect is not a server error
+	rec.Span("db:GetUserByUsername").
+		HasAttribute("error.type", "canceled").
+		HasStatus(codes.Unset)
+}
+
+func TestPoolConfigFromEnv(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN_CONNS", "5")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "1h")
+	t.Setenv("DB_CONN_MAX_IDLE_TIME", "soon")
+
+	cfg := PoolConfigFromEnv()
+	want := DefaultPoolConfig()
+	want.MaxOpenConns = 5
+	want.ConnMaxLifetime = time.Hour
+	if cfg != want {
+		t.Errorf("PoolConfigFromEnv() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestConnectRetriesUntilReachable(t *testing.T) {
+	rec := spantest.New(t)
+
+	// The database cannot be opened until its directory exists
+	dir := filepath.Join(t.TempDir(), "data")
+	db, err := NewSQLiteDatabase(filepath.Join(dir, "users.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		os.Mkdir(dir, 0o755)
+	}()
+
+	cfg := ConnectConfig{Timeout: 5 * time.Second, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond}
+	if err := db.Connect(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	var attempts []sdktrace.ReadOnlySpan
+	for _, s := range rec.Spans() {
+		if s.Name() == "db.connect" {
+			attempts = append(attempts, s)
+		}
+	}
+	if len(attempts) < 2 {
+		t.Fatalf("got %d db.connect spans, want a failed attempt before the successful one", len(attempts))
+	}
+	if first := attempts[0]; first.Status().Code != codes.Error {
+		t.Errorf("first attempt status = %v, want Error", first.Status())
+	}
+	if last := attempts[len(attempts)-1]; last.Status().Code != codes.Unset {
+		t.Errorf("last attempt status = %v, want Unset", last.Status())
+	}
+	rec.Span("db.connect").
+		HasAttribute("db.system", "sqlite").
+		HasAttribute("apm.db.connect.attempt", 1).
+		HasAttributeKey("apm.db.connect.backoff_ms")
+}
+
+func TestConnectGivesUpAtDeadline(t *testing.T) {
+	spantest.New(t)
+
+	db, err := NewSQLiteDatabase(filepath.Join(t.TempDir(), "missing", "users.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	start := time.Now()
+	cfg := ConnectConfig{Timeout: 100 * time.Millisecond, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond}
+	err = db.Connect(context.Background(), cfg)
+	if err == nil {
+		t.Fatal("Connect to an unreachable database succeeded")
+	}
+	if !strings.Contains(err.Error(), "attempts") {
+		t.Errorf("Connect error = %v, want the number of attempts", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Connect took %s, want it bounded by the 100ms timeout", elapsed)
+	}
+}
+
+func TestConnectConfigFromEnv(t *testing.T) {
+	t.Setenv("DB_CONNECT_TIMEOUT", "0")
+	t.Setenv("DB_CONNECT_MAX_BACKOFF", "1m")
+
+	cfg := ConnectConfigFromEnv()
+	want := DefaultConnectConfig()
+	want.Timeout = 0
+	want.MaxBackoff = time.Minute
+	if cfg != want {
+		t.Errorf("ConnectConfigFromEnv() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := jitter(time.Second); d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("jitter(1s) = %s, want between 500ms and 1s", d)
+		}
+	}
+}
+
+func TestSQLiteMigrationsDown(t *testing.T) {
+	ctx := context.Background()
+	_, db := newTestSQLiteRepository(t)
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reverted, err := migrator.Down(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reverted != 2 {
+		t.Errorf("reverted %d migrations, want 2", reverted)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt != nil {
+			t.Errorf("migration %d still applied after down", s.Version)
+		}
+	}
+
+	// Up is repeatable after a full down
+	if applied, err := migrator.Up(ctx); err != nil || applied != 2 {
+		t.Errorf("Up after down = %d, %v; want 2, nil", applied, err)
+	}
+}
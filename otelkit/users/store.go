@@ -0,0 +1,42 @@
+package users
+
+import (
+	"context"
+)
+
+// UserStore persists users. Implementations return errors wrapping the
+// sentinel errors in errors.go and record them on their own spans.
+type UserStore interface {
+	// CreateUser creates a new user
+	CreateUser(ctx context.Context, req CreateUserRequest) (*User, error)
+	// GetUserByUsername retrieves a user by username
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	// GetAllUsers retrieves a page of users matching the query
+	GetAllUsers(ctx context.Context, q ListUsersQuery) (*UserPage, error)
+	// UpdateUser updates an existing user
+	UpdateUser(ctx context.Context, username string, req UpdateUserRequest) (*User, error)
+	// DeleteUser deletes a user by username
+	DeleteUser(ctx context.Context, username string) error
+}
+
+var (
+	_ UserStore = (*UserRepository)(nil)
+	_ UserStore = (*MemoryStore)(nil)
+)
+
+// finishPage trims the extra user fetched beyond q.Limit to detect a next
+// page and sets the fields used to continue from it
+func finishPage(page *UserPage, q ListUsersQuery) {
+	if len(page.Users) <= q.Limit {
+		return
+	}
+
+	page.Users = page.Users[:q.Limit]
+	page.HasMore = true
+	if q.Sort == "username" {
+		page.NextCursor = EncodeCursor(page.Users[len(page.Users)-1].Username)
+	}
+	if q.Cursor == "" {
+		page.NextOffset = q.Offset + q.Limit
+	}
+}
@@ -3,19 +3,41 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"otelkit/apmattr"
+	"otelkit/httpclient"
+	"otelkit/telemetry"
+
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
 var tracer trace.Tracer
 
+// externalAPIURL is the endpoint callExternalAPI fetches; JSONPlaceholder is a free test API
+var externalAPIURL = "https://jsonplaceholder.typicode.com/posts/1"
+
+// externalAPIClient propagates the trace context to the external API and
+// traces every request it sends, including retries and redirects
+var externalAPIClient = httpclient.New(httpclient.Config{
+	TracerName:   "go-otel-demo",
+	Timeout:      10 * time.Second,
+	MaxRetries:   2,
+	RetryBackoff: 100 * time.Millisecond,
+})
+
+// shutdownTimeout bounds how long in-flight requests and span export may take on exit
+const shutdownTimeout = 10 * time.Second
+
 func init() {
 	// Get the global tracer
 	// Auto-instrumentation will set up the tracer provider for us
@@ -77,14 +99,15 @@ func apiCallHandler(w http.ResponseWriter, r *http.Request) {
 	// Create a custom span to add our attributes
 	ctx, span := tracer.Start(ctx, "handle_api_button_click")
 	defer span.End()
+	telemetry.PromoteBaggage(ctx, span)
 
 	// Set custom attributes on the span
 	span.SetAttributes(
-		attribute.String("user.action", "button_click"),
-		attribute.String("operation", "external_api_call"),
-		attribute.String("apm.custom.attribute", "demo_value"),
-		attribute.String("app.component", "api_handler"),
-		attribute.String("apm.business.operation", "fetch_post_data"),
+		apmattr.UserAction.String("button_click"),
+		apmattr.Operation.String("external_api_call"),
+		apmattr.CustomAttribute.String("demo_value"),
+		apmattr.Component.String("api_handler"),
+		apmattr.BusinessOperation.String("fetch_post_data"),
 	)
 
 	log.Printf("Button clicked - calling external API")
@@ -93,8 +116,8 @@ func apiCallHandler(w http.ResponseWriter, r *http.Request) {
 	result, err := callExternalAPI(ctx)
 	if err != nil {
 		span.SetAttributes(
-			attribute.Bool("apm.error", true),
-			attribute.String("apm.error.message", err.Error()),
+			apmattr.Error.Bool(true),
+			apmattr.ErrorMessage.String(err.Error()),
 		)
 		span.RecordError(err)
 
@@ -105,8 +128,8 @@ func apiCallHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Mark success
 	span.SetAttributes(
-		attribute.Bool("sapm.uccess", true),
-		attribute.String("apm.result.type", "json"),
+		apmattr.Success.Bool(true),
+		apmattr.ResultType.String("json"),
 	)
 
 	// Send response
@@ -119,22 +142,23 @@ func testAttributesHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Create a span
-	_, span := tracer.Start(ctx, "test_all_attribute_types")
+	ctx, span := tracer.Start(ctx, "test_all_attribute_types")
 	defer span.End()
+	telemetry.PromoteBaggage(ctx, span)
 
 	// Set attributes of all requested types
 	span.SetAttributes(
 		// Primitives
-		attribute.Bool("apm.test.bool", true),
-		attribute.Int64("apm.test.int64", 9876543210),
-		attribute.Float64("apm.test.float64", 123.456),
-		attribute.String("apm.test.string", "hello world"),
+		apmattr.TestBool.Bool(true),
+		apmattr.TestInt64.Int64(9876543210),
+		apmattr.TestFloat64.Float64(123.456),
+		apmattr.TestString.String("hello world"),
 
 		// Slices
-		attribute.BoolSlice("apm.test.bool_slice", []bool{true, false, true}),
-		attribute.Int64Slice("apm.test.int64_slice", []int64{10, 20, 30}),
-		attribute.Float64Slice("apm.test.float64_slice", []float64{1.5, 2.5, 3.5}),
-		attribute.StringSlice("apm.test.string_slice", []string{"apple", "banana", "cherry"}),
+		apmattr.TestBoolSlice.BoolSlice([]bool{true, false, true}),
+		apmattr.TestInt64Slice.Int64Slice([]int64{10, 20, 30}),
+		apmattr.TestFloat64Slice.Float64Slice([]float64{1.5, 2.5, 3.5}),
+		apmattr.TestStringSlice.StringSlice([]string{"apple", "banana", "cherry"}),
 	)
 
 	response := map[string]interface{}{
@@ -154,41 +178,36 @@ func callExternalAPI(ctx context.Context) (map[string]interface{}, error) {
 	// Create a custom span for the external API call logic
 	ctx, span := tracer.Start(ctx, "call_external_api")
 	defer span.End()
+	telemetry.PromoteBaggage(ctx, span)
 
-	// Using JSONPlaceholder as a free test API
-	apiURL := "https://jsonplaceholder.typicode.com/posts/1"
+	apiURL := externalAPIURL
 
 	// Set custom attributes
 	span.SetAttributes(
-		attribute.String("apm.external.api.url", apiURL),
-		attribute.String("apm.external.api.method", "GET"),
-		attribute.String("apm.custom.request.id", "req-12345"),
-		attribute.String("apm.external.api.provider", "jsonplaceholder"),
-		attribute.String("apm.data.type", "post"),
+		apmattr.ExternalAPIURL.String(apiURL),
+		apmattr.ExternalAPIMethod.String("GET"),
+		apmattr.CustomRequestID.String("req-12345"),
+		apmattr.ExternalAPIProvider.String("jsonplaceholder"),
+		apmattr.DataType.String("post"),
 	)
 
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		span.RecordError(err)
-		span.SetAttributes(attribute.String("apm.error.type", "request_creation_failed"))
+		span.SetAttributes(apmattr.ErrorType.String("request_creation_failed"))
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Add custom header
 	req.Header.Set("User-Agent", "GoOtelDemo/1.0")
 
-	// Make the request
+	// Make the request; the client injects the trace context and baggage
 	startTime := time.Now()
-	resp, err := client.Do(req)
+	resp, err := externalAPIClient.Do(req)
 	if err != nil {
 		span.RecordError(err)
-		span.SetAttributes(attribute.String("apm.error.type", "http_request_failed"))
+		span.SetAttributes(apmattr.ErrorType.String("http_request_failed"))
 		return nil, fmt.Errorf("failed to call external API: %w", err)
 	}
 	defer resp.Body.Close()
@@ -197,10 +216,10 @@ func callExternalAPI(ctx context.Context) (map[string]interface{}, error) {
 
 	// Set response attributes
 	span.SetAttributes(
-		attribute.Int64("apm.external.api.duration_ms", duration.Milliseconds()),
-		attribute.Int("apm.external.api.status_code", resp.StatusCode),
-		attribute.String("apm.external.api.status", resp.Status),
-		attribute.Int64("apm.external.api.response.content_length", resp.ContentLength),
+		apmattr.ExternalAPIDurationMS.Int64(duration.Milliseconds()),
+		apmattr.ExternalAPIStatusCode.Int(resp.StatusCode),
+		apmattr.ExternalAPIStatus.String(resp.Status),
+		apmattr.ExternalAPIResponseContentLength.Int64(resp.ContentLength),
 	)
 
 	log.Printf("External API called: status=%d, duration=%dms", resp.StatusCode, duration.Milliseconds())
@@ -209,19 +228,19 @@ func callExternalAPI(ctx context.Context) (map[string]interface{}, error) {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		span.RecordError(err)
-		span.SetAttributes(attribute.String("error.type", "response_read_failed"))
+		span.SetAttributes(apmattr.ErrorType.String("response_read_failed"))
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	span.SetAttributes(
-		attribute.Int("apm.external.api.response.body_size_bytes", len(body)),
+		apmattr.ExternalAPIResponseBodySize.Int(len(body)),
 	)
 
 	// Parse JSON response
 	var result map[string]interface{}
 	if err := json.Unmarshal(body, &result); err != nil {
 		span.RecordError(err)
-		span.SetAttributes(attribute.String("apm.error.type", "json_parse_failed"))
+		span.SetAttributes(apmattr.ErrorType.String("json_parse_failed"))
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
@@ -235,17 +254,25 @@ func callExternalAPI(ctx context.Context) (map[string]interface{}, error) {
 	}
 
 	span.SetAttributes(
-		attribute.Bool("apm.response.parsed", true),
+		apmattr.ResponseParsed.Bool(true),
 	)
 
 	return result, nil
 }
 
 func main() {
+	// Bootstrap the SDK TracerProvider when running without eBPF auto-instrumentation
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), telemetry.ConfigFromEnv("go-otel-demo"))
+	if err != nil {
+		log.Fatalf("Failed to set up telemetry: %v", err)
+	}
+
 	// Register handlers
-	http.HandleFunc("/", homeHandler)
-	http.HandleFunc("/api/call", apiCallHandler)
-	http.HandleFunc("/api/test-attributes", testAttributesHandler)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", homeHandler)
+	mux.HandleFunc("/api/call", apiCallHandler)
+	mux.HandleFunc("/api/test-attributes", testAttributesHandler)
+	mux.Handle("/debug/telemetry", telemetry.DiagnosticsHandler())
 
 	// Start server
 	port := "8082"
@@ -253,7 +280,31 @@ func main() {
 	log.Printf("Open http://localhost:%s in your browser", port)
 	log.Printf("Ready for OpenTelemetry auto-instrumentation v0.22.1")
 
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatal(err)
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Continue the caller's trace when no eBPF instrumentation owns server
+	// spans, and promote the gateway's baggage (tenant, customer tier) to
+	// span attributes
+	handler := telemetry.ServerMiddleware(telemetry.BaggageMiddleware(mux))
+	srv := &http.Server{Addr: ":" + port, Handler: handler}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Drain in-flight requests, then flush queued spans before exiting
+	<-sigCtx.Done()
+	log.Println("Shutting down server")
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
+	if err := shutdownTelemetry(shutdownCtx); err != nil {
+		log.Printf("Error shutting down telemetry: %v", err)
 	}
 }
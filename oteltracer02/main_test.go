@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"otelkit/spantest"
+	"otelkit/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// stubExternalAPI points callExternalAPI at a local server for the test
+func stubExternalAPI(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	previous := externalAPIURL
+	externalAPIURL = srv.URL + "/posts/1"
+	t.Cleanup(func() { externalAPIURL = previous })
+}
+
+func TestAPICallHandler(t *testing.T) {
+	rec := spantest.New(t)
+	stubExternalAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1,"title":"post"}`))
+	})
+
+	w := httptest.NewRecorder()
+	apiCallHandler(w, httptest.NewRequest(http.MethodGet, "/api/call", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	rec.Span("handle_api_button_click").
+		IsRoot().
+		HasAttribute("apm.custom.attribute", "demo_value").
+		HasAttribute("apm.business.operation", "fetch_post_data").
+		HasAttribute("apm.result.type", "json").
+		HasAttribute("apm.success", true).
+		HasStatus(codes.Unset)
+	rec.Span("call_external_api").
+		ChildOf("handle_api_button_click").
+		HasAttribute("apm.external.api.method", "GET").
+		HasAttribute("apm.external.api.status_code", http.StatusOK).
+		HasAttribute("apm.response.parsed", true).
+		HasAttributeKey("apm.external.api.duration_ms")
+}
+
+func TestAPICallHandlerError(t *testing.T) {
+	rec := spantest.New(t)
+	stubExternalAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	})
+
+	w := httptest.NewRecorder()
+	apiCallHandler(w, httptest.NewRequest(http.MethodGet, "/api/call", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+
+	rec.Span("call_external_api").
+		HasEvent("exception").
+		HasStatus(codes.Unset).
+		HasAttribute("apm.error.type", "json_parse_failed").
+		NoAttribute("apm.response.parsed")
+	rec.Span("handle_api_button_click").
+		HasAttribute("apm.error", true).
+		HasAttributeKey("apm.error.message").
+		NoAttribute("apm.result.type").
+		NoAttribute("apm.success")
+}
+
+func TestTestAttributesHandler(t *testing.T) {
+	rec := spantest.New(t)
+
+	w := httptest.NewRecorder()
+	testAttributesHandler(w, httptest.NewRequest(http.MethodGet, "/api/test-attributes", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	rec.Span("test_all_attribute_types").
+		HasAttribute("apm.test.bool", true).
+		HasAttribute("apm.test.int64", int64(9876543210)).
+		HasAttribute("apm.test.float64", 123.456).
+		HasAttribute("apm.test.string", "hello world").
+		HasAttribute("apm.test.bool_slice", []bool{true, false, true}).
+		HasAttribute("apm.test.int64_slice", []int64{10, 20, 30}).
+		HasAttribute("apm.test.float64_slice", []float64{1.5, 2.5, 3.5}).
+		HasAttribute("apm.test.string_slice", []string{"apple", "banana", "cherry"})
+}
+
+func TestAPICallHandlerPropagatesContext(t *testing.T) {
+	rec := spantest.New(t)
+
+	propagator, err := telemetry.NewPropagator(telemetry.DefaultPropagators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagator)
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	var got http.Header
+	stubExternalAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Write([]byte(`{"id":1,"title":"post"}`))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/call", nil)
+	req.Header.Set("baggage", "tenant.id=acme")
+	telemetry.BaggageMiddleware(http.HandlerFunc(apiCallHandler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	client := rec.Span("GET").ChildOf("call_external_api").ReadOnly().SpanContext()
+	if want := client.TraceID().String() + "-" + client.SpanID().String(); !strings.Contains(got.Get("traceparent"), want) || !strings.HasPrefix(got.Get("b3"), want) {
+		t.Errorf("external API received traceparent %q and b3 %q, want the client span %s", got.Get("traceparent"), got.Get("b3"), want)
+	}
+	if got.Get("baggage") != "tenant.id=acme" {
+		t.Errorf("external API received baggage %q, want tenant.id=acme", got.Get("baggage"))
+	}
+}
+
+// TestTraceShapes locks down the trace tree of every API endpoint; run
+// go test -update to regenerate testdata/traces after an intended change
+func TestTraceShapes(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":1,"title":"post"}`))
+	}
+	broken := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		target   string
+		external http.HandlerFunc
+	}{
+		{"api_call", apiCallHandler, "/api/call", ok},
+		{"api_call_error", apiCallHandler, "/api/call", broken},
+		{"test_attributes", testAttributesHandler, "/api/test-attributes", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := spantest.New(t)
+			if tt.external != nil {
+				stubExternalAPI(t, tt.external)
+			}
+
+			tt.handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+			rec.Golden(filepath.Join("testdata", "traces", tt.name+".json"))
+		})
+	}
+}